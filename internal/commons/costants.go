@@ -1,5 +1,7 @@
 package commons
 
+import "time"
+
 // Window
 var WindowWidth = float32(800)
 var WindowHeight = float32(600)
@@ -15,38 +17,184 @@ var AccessToken = "Access Token"
 var ExcludeWinners = "Exclude previous winners"
 var RefreshToken = "Refresh Token"
 var CampaignId = "Campaign Id"
+var CallbackURI = "Redirect URI"
+var CreatorAccessToken = "Creator Access Token"
+var CreatorRefreshToken = "Creator Refresh Token"
+var NoTierName = "No tier name"
+var DataDirectory = "Data directory"
+var CampaignName = "Campaign name"
 
 // Patreon variables
 var RedirectURI = "http://localhost:8080"
-var WithIncludes = "currently_entitled_tiers"
-var MemberFields = []string{"full_name", "patron_status", "last_charge_status"}
-var TierFields = []string{"title"}
+var AuthTimeout = 5 * time.Minute
+var Scopes = []string{"identity", "campaigns", "campaigns.members", "campaigns.members[email]"}
+var WithIncludes = []string{"currently_entitled_tiers", "user"}
+var MemberFields = []string{"full_name", "patron_status", "last_charge_status", "email", "currently_entitled_amount_cents",
+	"lifetime_support_cents", "pledge_relationship_start"}
+var TierFields = []string{"title", "amount_cents", "published"}
+var UserFields = []string{"full_name"}
+var NoTierID = "no_tier"
+var DefaultNoTierName = "No tier"
+var PageSizes = []int{100, 250, 500, 1000}
+var DefaultPageSize = 500
+var MaxFetchRetries = 5
+var RetryBaseDelay = 2 * time.Second
+var RetryMaxDelay = time.Minute
+var SnapshotRetentionOptions = []int{0, 30, 90, 180, 365}
+
+// Participants import: the header names every participant field is recognised by, in order of preference,
+// and the columns that identify a members export of the Patreon dashboard
+var ImportColumnAliases = struct {
+	Name    []string
+	Tier    []string
+	Entries []string
+	ID      []string
+	Email   []string
+}{
+	Name:    []string{"name", "full name", "full_name", "display name", "username", "participant"},
+	Tier:    []string{"tier", "tier title", "level", "reward"},
+	Entries: []string{"entries", "chances", "tickets"},
+	ID:      []string{"user id", "id", "member id", "patreon id"},
+	Email:   []string{"email", "e-mail", "email address"},
+}
+var PatreonExportColumns = []string{"name", "email", "patron status", "pledge amount", "tier", "last charge status", "user id"}
 
 // Preferences keys
+var AllowedChargeStatuses = "allowedChargeStatuses"
+var AllowedPatronStatuses = "allowedPatronStatuses"
+var AuthMode = "authMode"
+var CampaignChancesPrefix = "campaignChances."
+var CampaignProfiles = "campaignProfiles"
 var ChancesRule = "chancesRule"
 var ChancesPerUser = "chancesPerUser"
+var CreatorTokenExpiry = "creatorTokenExpiry"
+var DataMigrated = "dataMigrated"
+var DrawSources = "drawSources"
+var LegacyTierChancesPrefix = "chances"
+var MultiTierPolicy = "multiTierPolicy"
 var NumberOfWinners = "numberOfWinners"
+var PageSize = "pageSize"
+var ProfilePrefix = "profile."
+var SelectedSnapshot = "selectedSnapshot"
+var SnapshotRetentionDays = "snapshotRetentionDays"
+var SourceSettingsPrefix = "source."
 var TestMode = "testMode"
+var TierChancesIDs = "tierChancesIDs"
+var TierChancesPrefix = "tierChances."
+var TierChancesTitlePrefix = "tierChancesTitle."
+var TokenExpiry = "tokenExpiry"
 var UseRealData = "useRealData"
 
+// Chances rules stored under the ChancesRule preference, with the translation key of their label
+var ChancesRuleIDs = struct {
+	Equal      string
+	ByTier     string
+	ByCampaign string
+}{
+	Equal:      "equal",
+	ByTier:     "by_tier",
+	ByCampaign: "by_campaign",
+}
+
 // Lists
-var ChancesRules = []string{I18n.AllEqualChances, I18n.ChancesByTier}
+var ChancesRules = []string{ChancesRuleIDs.Equal, ChancesRuleIDs.ByTier, ChancesRuleIDs.ByCampaign}
+
+var ChancesRuleLabels = map[string]string{
+	ChancesRuleIDs.Equal:      I18n.AllEqualChances,
+	ChancesRuleIDs.ByTier:     I18n.ChancesByTier,
+	ChancesRuleIDs.ByCampaign: I18n.ChancesByCampaign,
+}
+
+// Settings kept separately for every campaign profile, see ProfileKey
+var ProfileSettings = []string{ClientId, ClientSecret, CampaignId, CampaignName, CallbackURI, CreatorAccessToken, CreatorRefreshToken,
+	AccessToken, RefreshToken, TokenExpiry, CreatorTokenExpiry, AuthMode}
+
+// Member statuses reported by Patreon, with the ones eligible by default
+var PatronStatuses = []string{"active_patron", "declined_patron", "former_patron"}
+var ChargeStatuses = []string{"Paid", "Declined", "Deleted", "Pending", "Refunded", "Fraud", "Other"}
+var DefaultPatronStatuses = []string{"active_patron"}
+var DefaultChargeStatuses = []string{"Paid"}
+
+var PatronStatusLabels = map[string]string{
+	"active_patron":   I18n.PatronStatusActive,
+	"declined_patron": I18n.PatronStatusDeclined,
+	"former_patron":   I18n.PatronStatusFormer,
+}
+
+var ChargeStatusLabels = map[string]string{
+	"Paid":     I18n.ChargeStatusPaid,
+	"Declined": I18n.ChargeStatusDeclined,
+	"Deleted":  I18n.ChargeStatusDeleted,
+	"Pending":  I18n.ChargeStatusPending,
+	"Refunded": I18n.ChargeStatusRefunded,
+	"Fraud":    I18n.ChargeStatusFraud,
+	"Other":    I18n.ChargeStatusOther,
+}
+
+// Policies for members entitled to several tiers, stored under the MultiTierPolicy preference
+var MultiTierPolicies = struct {
+	Highest string
+	All     string
+}{
+	Highest: "highest",
+	All:     "all",
+}
+
+// Authorization modes stored under the AuthMode preference
+var AuthModes = struct {
+	Browser string
+	Manual  string
+	Creator string
+}{
+	Browser: "browser",
+	Manual:  "manual",
+	Creator: "creator",
+}
+
+// Log file name, in the data directory
+var LogFileName = "log.txt"
 
 // JSON file names
 var StructuredData = struct {
-	OutputPath        string
-	RealDataFileName  string
-	TestDataFileName  string
-	RealTiersFileName string
-	TestTiersFileName string
-	WinnersFileName   string
+	OutputPath             string
+	RealDataFileName       string
+	TestDataFileName       string
+	RealTiersFileName      string
+	TestTiersFileName      string
+	WinnersFileName        string
+	FetchProgressFileName  string
+	SnapshotsDir           string
+	TestSnapshotsDir       string
+	OverridesFileName      string
+	TestOverridesFileName  string
+	ImportDataFileName     string
+	ImportTiersFileName    string
+	TwitchEntriesFileName  string
+	TwitchTiersFileName    string
+	YouTubeEntriesFileName string
+	YouTubeTiersFileName   string
+	DiscordMembersFileName string
+	DiscordTiersFileName   string
 }{
-	OutputPath:        "structured_data/",
-	RealDataFileName:  "eligle_patreons.json",
-	TestDataFileName:  "eligle_patreons_test.json",
-	RealTiersFileName: "tiers.json",
-	TestTiersFileName: "tiers_test.json",
-	WinnersFileName:   "winners.json",
+	OutputPath:             "structured_data/",
+	RealDataFileName:       "eligle_patreons.json",
+	TestDataFileName:       "eligle_patreons_test.json",
+	RealTiersFileName:      "tiers.json",
+	TestTiersFileName:      "tiers_test.json",
+	WinnersFileName:        "winners.json",
+	FetchProgressFileName:  "fetch_progress.json",
+	SnapshotsDir:           "snapshots",
+	TestSnapshotsDir:       "snapshots_test",
+	OverridesFileName:      "draw_overrides.json",
+	TestOverridesFileName:  "draw_overrides_test.json",
+	ImportDataFileName:     "imported_participants.json",
+	ImportTiersFileName:    "imported_tiers.json",
+	TwitchEntriesFileName:  "twitch_entries.json",
+	TwitchTiersFileName:    "twitch_tiers.json",
+	YouTubeEntriesFileName: "youtube_entries.json",
+	YouTubeTiersFileName:   "youtube_tiers.json",
+	DiscordMembersFileName: "discord_members.json",
+	DiscordTiersFileName:   "discord_tiers.json",
 }
 
 // Assets
@@ -114,27 +262,133 @@ var Settings = struct {
 // Translation keys
 
 var I18n = struct {
+	AddCampaign           string
+	AddParticipant        string
 	AllEqualChances       string
+	AuthCloseWindow       string
+	AuthCode              string
+	AuthFailed            string
+	AuthMode              string
+	AuthModeBrowser       string
+	AuthModeCreator       string
+	AuthModeManual        string
+	AuthSuccess           string
+	Campaign              string
 	Cancel                string
+	ChancesByCampaign     string
 	ChancesByTier         string
 	ChancesPerPatreon     string
+	ChargeStatusDeclined  string
+	ChargeStatusDeleted   string
+	ChargeStatusFraud     string
+	ChargeStatusOther     string
+	ChargeStatusPaid      string
+	ChargeStatusPending   string
+	ChargeStatusRefunded  string
+	ChatChannel           string
 	ClearWinners          string
 	Close                 string
+	CloseEntries          string
 	ConfirmClearWinners   string
+	ConfirmRemoveCampaign string
 	Congrats              string
+	Copy                  string
+	DataDirHint           string
+	DiscordAPI            string
+	DiscordEmoji          string
+	DiscordMessage        string
+	DiscordRoles          string
+	DiscordServer         string
+	DiscordToken          string
+	Edit                  string
+	Eligibility           string
+	EntriesCount          string
+	EntriesFailed         string
+	EntriesOpen           string
+	EntriesSummary        string
+	EntryKeyword          string
 	ExcludeWinners        string
 	ErrorFetchingPatreons string
+	FetchCancelled        string
 	FetchingPatreons      string
+	FetchInterrupted      string
+	FetchPages            string
+	FetchResumed          string
+	FetchSharedPatreons   string
+	FetchStepAuth         string
+	FetchStepFetch        string
+	FetchStepFetchTotal   string
+	FetchStepWrite        string
+	FetchSummary          string
+	Import                string
+	ImportedParticipants  string
+	ImportEmail           string
+	ImportEntries         string
+	ImportFailed          string
+	ImportID              string
+	ImportName            string
+	ImportNotMapped       string
+	ImportParticipants    string
+	ImportPatreonExport   string
+	ImportRows            string
+	ImportSummary         string
+	ImportTier            string
+	LastChargeStatus      string
+	LastEntry             string
+	LatestSnapshot        string
+	Login                 string
+	MainCampaign          string
+	ManualLogin           string
+	ManualLoginInfo       string
 	MissingData           string
+	MultiTierAll          string
+	MultiTierHighest      string
+	MultiTierPolicy       string
 	NewDraw               string
 	No                    string
+	NoEntries             string
+	NoParticipantsLeft    string
 	NoPatreons            string
+	NumberOfWinners       string
+	OpenEntries           string
+	OverrideAdded         string
+	OverrideEdited        string
+	OverrideRemoved       string
+	PageSize              string
+	ParticipantEntries    string
+	ParticipantName       string
+	ParticipantTier       string
 	PatreonsList          string
+	PatronStatus          string
+	PatronStatusActive    string
+	PatronStatusDeclined  string
+	PatronStatusFormer    string
 	PreviousWinners       string
 	ReadLogs              string
 	Ready                 string
 	RefreshPatreonsList   string
+	Remove                string
+	RemoveCampaign        string
+	ResetChanges          string
+	Restore               string
+	RetentionDays         string
+	RetentionForever      string
+	RosterChanges         string
+	RosterDowngraded      string
+	RosterJoined          string
+	RosterLeft            string
+	RosterStatusChanged   string
+	RosterTierChanged     string
+	RosterUpgraded        string
+	Save                  string
 	Settings              string
+	SkippedMembers        string
+	Snapshot              string
+	SnapshotLoadFailed    string
+	SnapshotRetention     string
+	Sources               string
+	SourceSettings        string
+	StaleTierChances      string
 	Success               string
 	SuccessfulReceive     string
 	TestData              string
@@ -143,32 +397,145 @@ var I18n = struct {
 	TestMode              string
 	TestModeWrn           string
 	TestRealData          string
+	TwitchServer          string
+	TwitchToken           string
+	TwitchUsername        string
+	WinnerPlace           string
 	Yes                   string
 	Winner                string
 	WinnersCleared        string
 	WinnersListCleared    string
+	YouTubeAPI            string
+	YouTubeAPIKey         string
+	YouTubeVideo          string
 }{
+	AddCampaign:           "add_campaign",
+	AddParticipant:        "add_participant",
 	AllEqualChances:       "all_equal_chances",
+	AuthCloseWindow:       "auth_close_window",
+	AuthCode:              "auth_code",
+	AuthFailed:            "auth_failed",
+	AuthMode:              "auth_mode",
+	AuthModeBrowser:       "auth_mode_browser",
+	AuthModeCreator:       "auth_mode_creator",
+	AuthModeManual:        "auth_mode_manual",
+	AuthSuccess:           "auth_success",
+	Campaign:              "campaign",
 	Cancel:                "cancel",
+	ChancesByCampaign:     "chances_by_campaign",
 	ChancesByTier:         "chances_by_tier",
 	ChancesPerPatreon:     "chances_per_patreon",
+	ChargeStatusDeclined:  "charge_status_declined",
+	ChargeStatusDeleted:   "charge_status_deleted",
+	ChargeStatusFraud:     "charge_status_fraud",
+	ChargeStatusOther:     "charge_status_other",
+	ChargeStatusPaid:      "charge_status_paid",
+	ChargeStatusPending:   "charge_status_pending",
+	ChargeStatusRefunded:  "charge_status_refunded",
+	ChatChannel:           "chat_channel",
 	ClearWinners:          "clear_winners",
 	Close:                 "close",
+	CloseEntries:          "close_entries",
 	ConfirmClearWinners:   "confirm_clear_winners",
+	ConfirmRemoveCampaign: "confirm_remove_campaign",
 	Congrats:              "congratulations",
+	Copy:                  "copy",
+	DataDirHint:           "data_dir_hint",
+	DiscordAPI:            "discord_api",
+	DiscordEmoji:          "discord_emoji",
+	DiscordMessage:        "discord_message",
+	DiscordRoles:          "discord_roles",
+	DiscordServer:         "discord_server",
+	DiscordToken:          "discord_token",
+	Edit:                  "edit",
+	Eligibility:           "eligibility",
+	EntriesCount:          "entries_count",
+	EntriesFailed:         "entries_failed",
+	EntriesOpen:           "entries_open",
+	EntriesSummary:        "entries_summary",
+	EntryKeyword:          "entry_keyword",
 	ExcludeWinners:        "exclude_winners",
 	ErrorFetchingPatreons: "error_fetching_patreons",
+	FetchCancelled:        "fetch_cancelled",
 	FetchingPatreons:      "fetching_patreons",
+	FetchInterrupted:      "fetch_interrupted",
+	FetchPages:            "fetch_pages",
+	FetchResumed:          "fetch_resumed",
+	FetchSharedPatreons:   "fetch_shared_patreons",
+	FetchStepAuth:         "fetch_step_auth",
+	FetchStepFetch:        "fetch_step_fetch",
+	FetchStepFetchTotal:   "fetch_step_fetch_total",
+	FetchStepWrite:        "fetch_step_write",
+	FetchSummary:          "fetch_summary",
+	Import:                "import",
+	ImportedParticipants:  "imported_participants",
+	ImportEmail:           "import_email",
+	ImportEntries:         "import_entries",
+	ImportFailed:          "import_failed",
+	ImportID:              "import_id",
+	ImportName:            "import_name",
+	ImportNotMapped:       "import_not_mapped",
+	ImportParticipants:    "import_participants",
+	ImportPatreonExport:   "import_patreon_export",
+	ImportRows:            "import_rows",
+	ImportSummary:         "import_summary",
+	ImportTier:            "import_tier",
+	LastChargeStatus:      "last_charge_status",
+	LastEntry:             "last_entry",
+	LatestSnapshot:        "latest_snapshot",
+	Login:                 "login",
+	MainCampaign:          "main_campaign",
+	ManualLogin:           "manual_login",
+	ManualLoginInfo:       "manual_login_info",
 	MissingData:           "missing_data",
+	MultiTierAll:          "multi_tier_all",
+	MultiTierHighest:      "multi_tier_highest",
+	MultiTierPolicy:       "multi_tier_policy",
 	NewDraw:               "new_draw",
 	No:                    "no",
+	NoEntries:             "no_entries",
+	NoParticipantsLeft:    "no_participants_left",
 	NoPatreons:            "no_patreons_found",
+	NumberOfWinners:       "number_of_winners",
+	OpenEntries:           "open_entries",
+	OverrideAdded:         "override_added",
+	OverrideEdited:        "override_edited",
+	OverrideRemoved:       "override_removed",
+	PageSize:              "page_size",
+	ParticipantEntries:    "participant_entries",
+	ParticipantName:       "participant_name",
+	ParticipantTier:       "participant_tier",
 	PatreonsList:          "patreons_list",
+	PatronStatus:          "patron_status",
+	PatronStatusActive:    "patron_status_active",
+	PatronStatusDeclined:  "patron_status_declined",
+	PatronStatusFormer:    "patron_status_former",
 	PreviousWinners:       "previous_winners",
 	ReadLogs:              "read_logs",
 	Ready:                 "ready",
 	RefreshPatreonsList:   "refresh_patreons_list",
+	Remove:                "remove",
+	RemoveCampaign:        "remove_campaign",
+	ResetChanges:          "reset_changes",
+	Restore:               "restore",
+	RetentionDays:         "retention_days",
+	RetentionForever:      "retention_forever",
+	RosterChanges:         "roster_changes",
+	RosterDowngraded:      "roster_downgraded",
+	RosterJoined:          "roster_joined",
+	RosterLeft:            "roster_left",
+	RosterStatusChanged:   "roster_status_changed",
+	RosterTierChanged:     "roster_tier_changed",
+	RosterUpgraded:        "roster_upgraded",
+	Save:                  "save",
 	Settings:              "settings",
+	SkippedMembers:        "skipped_members",
+	Snapshot:              "snapshot",
+	SnapshotLoadFailed:    "snapshot_load_failed",
+	SnapshotRetention:     "snapshot_retention",
+	Sources:               "sources",
+	SourceSettings:        "source_settings",
+	StaleTierChances:      "stale_tier_chances",
 	Success:               "success",
 	SuccessfulReceive:     "succsfull_received_patreons",
 	TestData:              "test_data",
@@ -177,8 +544,15 @@ var I18n = struct {
 	TestMode:              "test_mode",
 	TestModeWrn:           "test_mode_warning",
 	TestRealData:          "test_real_data",
+	TwitchServer:          "twitch_server",
+	TwitchToken:           "twitch_token",
+	TwitchUsername:        "twitch_username",
+	WinnerPlace:           "winner_place",
 	Yes:                   "yes",
 	Winner:                "winner",
 	WinnersCleared:        "winners_cleared",
 	WinnersListCleared:    "winners_list_cleared",
+	YouTubeAPI:            "youtube_api",
+	YouTubeAPIKey:         "youtube_api_key",
+	YouTubeVideo:          "youtube_video",
 }
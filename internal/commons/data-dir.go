@@ -0,0 +1,37 @@
+package commons
+
+import (
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+)
+
+// ResolveDataDir returns the directory where the application stores its data. In order of precedence:
+// the directory given on the command line, the one set in the preferences, the StructuredData.OutputPath
+// folder under the Fyne storage root of the app, or the same folder under the XDG data directory
+// ($XDG_DATA_HOME, or ~/.local/share) when the app has no storage root.
+func ResolveDataDir(app fyne.App, flagDir string) string {
+	if flagDir != "" {
+		return flagDir
+	}
+	if dir := app.Preferences().String(DataDirectory); dir != "" {
+		return dir
+	}
+	if root := app.Storage().RootURI(); root != nil && root.Path() != "" {
+		return filepath.Join(root.Path(), StructuredData.OutputPath)
+	}
+	return filepath.Join(xdgDataHome(), "pick-a-bro", StructuredData.OutputPath)
+}
+
+// xdgDataHome returns the base directory for user data files as defined by the XDG base directory specification.
+func xdgDataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(home, ".local", "share")
+}
@@ -2,6 +2,7 @@ package commons
 
 import (
 	"embed"
+	"encoding/json"
 
 	"fyne.io/fyne/v2"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -51,6 +52,37 @@ func EmbedLocales(bundle *i18n.Bundle) {
 	}
 }
 
+// GetAllTranslations returns the translations of the given key in every embedded locale.
+// Locales that cannot be read or do not contain the key are left out.
+func GetAllTranslations(key string) []string {
+	files, err := GetLocalesFS().ReadDir("locale")
+	if err != nil {
+		GetLogger().Printf("failed to list translation files: %v", err)
+		return nil
+	}
+
+	translations := []string{}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		data, err := GetLocalesFS().ReadFile("locale/" + file.Name())
+		if err != nil {
+			GetLogger().Printf("failed to read translation file: %v", err)
+			continue
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			GetLogger().Printf("failed to parse translation file: %v", err)
+			continue
+		}
+		if translation, ok := messages[key]; ok {
+			translations = append(translations, translation)
+		}
+	}
+	return translations
+}
+
 func GetImagesFS() *embed.FS {
 	return imagesFS
 }
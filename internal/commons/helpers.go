@@ -2,6 +2,7 @@ package commons
 
 import (
 	"log"
+	"path/filepath"
 
 	"fyne.io/fyne/v2"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -11,6 +12,7 @@ var pickABro fyne.App
 var preferences fyne.Preferences
 var loc *i18n.Localizer
 var logger *log.Logger
+var dataDir string
 
 func SetApplications(app fyne.App) {
 	pickABro = app
@@ -40,6 +42,32 @@ func SetLogger(l *log.Logger) {
 	logger = l
 }
 
+func SetDataDir(dir string) {
+	dataDir = dir
+}
+
+func GetDataDir() string {
+	return dataDir
+}
+
+// GetDataPath returns the path of the named file or directory in the data directory.
+// Without a data directory, the name is returned as is, relative to the working directory.
+func GetDataPath(name string) string {
+	if dataDir == "" {
+		return name
+	}
+	return filepath.Join(dataDir, name)
+}
+
+// ProfileKey returns the preferences key of a setting of the campaign profile with the given ID.
+// The default profile, with an empty ID, uses the key as is, so it keeps the settings of the single campaign versions.
+func ProfileKey(profileID string, key string) string {
+	if profileID == "" {
+		return key
+	}
+	return ProfilePrefix + profileID + "." + key
+}
+
 // GetTranslation retrieves the translation for the given key.
 // It uses the loc.Localize function from the i18n package to perform the translation.
 // If an error occurs during the translation process, an empty string is returned.
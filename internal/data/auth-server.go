@@ -0,0 +1,381 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"pick-a-bro/internal/commons"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/austinbspencer/patreon-go-wrapper"
+	"golang.org/x/oauth2"
+)
+
+var ErrAuthCancelled = errors.New("authorization cancelled")
+var ErrAuthTimeout = errors.New("authorization timed out")
+var ErrManualAuthRequired = errors.New("no valid authorization found, complete the manual login in the settings first")
+var ErrCreatorTokenMissing = errors.New("no creator access token set in the settings")
+
+// authPage is the page shown in the browser once the redirect from Patreon has been handled.
+var authPage = template.Must(template.New("auth").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif; text-align: center; margin-top: 15%;">
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+</body>
+</html>`))
+
+type authResult struct {
+	code string
+	err  error
+}
+
+// authRequest holds the parameters of a single authorization attempt of a profile.
+// The state protects the redirect against CSRF and the verifier is the PKCE secret sent with the code exchange.
+type authRequest struct {
+	profile     Profile
+	redirectURI string
+	state       string
+	verifier    string
+}
+
+// authServer is a short-lived HTTP server listening on the loopback interface for the OAuth redirect.
+// Every authorization attempt gets its own server and mux, so logging in again in the same session is safe.
+type authServer struct {
+	authRequest
+	server       *http.Server
+	listener     net.Listener
+	callbackPath string
+	result       chan authResult
+}
+
+// ManualAuthorization is an authorization completed by pasting the code instead of receiving the redirect,
+// for machines where no browser can be opened. URL is the page to open on any device.
+type ManualAuthorization struct {
+	authRequest
+	URL string
+}
+
+// authorize runs the OAuth authorization code flow of the profile through the browser and returns the obtained token.
+// It starts a callback server, opens the authorization page and waits for the redirect until the context
+// is cancelled or commons.AuthTimeout expires. The received code is then exchanged for a token.
+func authorize(ctx context.Context, profile Profile) (*oauth2.Token, error) {
+	srv, err := newAuthServer(profile)
+	if err != nil {
+		return nil, err
+	}
+	defer srv.close()
+
+	if err := openAuthURLInBrowser(srv.authorizationURL()); err != nil {
+		return nil, fmt.Errorf("%w, use the manual login in the settings instead", err)
+	}
+
+	code, err := srv.waitForCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return srv.fetchToken(ctx, code)
+}
+
+// newAuthServer creates and starts the callback server for the redirect URI configured in the profile.
+// The host of the redirect URI must be a loopback address. If the URI has no port or port 0,
+// a free port is selected and the redirect URI sent to Patreon is adjusted accordingly.
+func newAuthServer(profile Profile) (*authServer, error) {
+	redirect, err := url.Parse(configuredRedirectURI(profile))
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URI: %w", err)
+	}
+
+	host := redirect.Hostname()
+	if !isLoopback(host) {
+		return nil, fmt.Errorf("redirect URI %s must point to a loopback address", redirect)
+	}
+
+	port := redirect.Port()
+	if port == "" {
+		port = "0"
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the authorization server: %w", err)
+	}
+
+	redirect.Host = net.JoinHostPort(host, fmt.Sprint(listener.Addr().(*net.TCPAddr).Port))
+
+	callbackPath := redirect.Path
+	if callbackPath == "" {
+		callbackPath = "/"
+	}
+
+	srv := &authServer{
+		authRequest:  newAuthRequest(profile, redirect.String()),
+		listener:     listener,
+		callbackPath: callbackPath,
+		result:       make(chan authResult, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, srv.handleCallback)
+	srv.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := srv.server.Serve(listener); err != http.ErrServerClosed {
+			commons.GetLogger().Println(err)
+		}
+	}()
+
+	return srv, nil
+}
+
+// StartManualAuthorization prepares an authorization of the profile for the redirect URI configured in it
+// and returns it together with the URL the operator has to open.
+func StartManualAuthorization(profile Profile) *ManualAuthorization {
+	request := newAuthRequest(profile, configuredRedirectURI(profile))
+	return &ManualAuthorization{authRequest: request, URL: request.authorizationURL()}
+}
+
+// Complete finishes the manual authorization with the pasted input, which can be either the code
+// or the whole URL the browser was redirected to. The code is exchanged for a token, which is persisted
+// so the next fetch of the profile uses it directly.
+func (m *ManualAuthorization) Complete(ctx context.Context, input string) error {
+	input = strings.TrimSpace(input)
+	code := input
+	if redirected, err := url.Parse(input); err == nil && redirected.RawQuery != "" {
+		code, err = m.parseCallback(redirected.Query())
+		if err != nil {
+			return err
+		}
+	}
+	if code == "" {
+		return errors.New("authorization code is empty")
+	}
+
+	token, err := m.fetchToken(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	keys := oauthTokenKeys(m.profile)
+	saveToken(keys, token)
+	setClient(m.profile, newTokenSource(m.profile, keys, token), commons.AuthModes.Manual)
+	commons.GetLogger().Printf("Manual authorization of %s completed", m.profile.Name())
+	return nil
+}
+
+// newAuthRequest creates the parameters of a new authorization attempt of the profile for the given redirect URI.
+func newAuthRequest(profile Profile, redirectURI string) authRequest {
+	return authRequest{
+		profile:     profile,
+		redirectURI: redirectURI,
+		state:       randomString(),
+		verifier:    randomString(),
+	}
+}
+
+// authorizationURL returns the Patreon authorization URL including the state and the PKCE challenge.
+func (a *authRequest) authorizationURL() string {
+	challenge := sha256.Sum256([]byte(a.verifier))
+	authParams := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.profile.setting(commons.ClientId)},
+		"redirect_uri":          {a.redirectURI},
+		"scope":                 {strings.Join(commons.Scopes, " ")},
+		"state":                 {a.state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	return fmt.Sprintf("%s?%s", patreon.AuthorizationURL, authParams.Encode())
+}
+
+// parseCallback verifies the state of the redirect query and returns the code or the error reported by Patreon.
+func (a *authRequest) parseCallback(query url.Values) (string, error) {
+	switch {
+	case query.Get("state") != a.state:
+		return "", errors.New("authorization response has an invalid state")
+	case query.Get("error") != "":
+		return "", fmt.Errorf("authorization denied: %s", strings.TrimSpace(query.Get("error")+" "+query.Get("error_description")))
+	case query.Get("code") == "":
+		return "", errors.New("authorization response did not contain a code")
+	}
+	return query.Get("code"), nil
+}
+
+// handleCallback handles the redirect from Patreon. It verifies the state parameter,
+// extracts the code or the error reported by Patreon, renders the result page and hands the result to waitForCode.
+func (a *authServer) handleCallback(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != a.callbackPath {
+		http.NotFound(w, r)
+		return
+	}
+
+	var result authResult
+	result.code, result.err = a.parseCallback(r.URL.Query())
+
+	writeAuthPage(w, result.err)
+
+	select {
+	case a.result <- result:
+	default:
+	}
+}
+
+// waitForCode blocks until the redirect has been handled, the context is cancelled or commons.AuthTimeout expires.
+func (a *authServer) waitForCode(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.AuthTimeout)
+	defer cancel()
+
+	select {
+	case result := <-a.result:
+		return result.code, result.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", ErrAuthTimeout
+		}
+		return "", ErrAuthCancelled
+	}
+}
+
+// close shuts the callback server down.
+func (a *authServer) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := a.server.Shutdown(ctx); err != nil {
+		commons.GetLogger().Println(err)
+	}
+}
+
+// writeAuthPage renders the success or failure page in the browser.
+func writeAuthPage(w http.ResponseWriter, authErr error) {
+	page := struct {
+		Title   string
+		Message string
+	}{
+		Title:   commons.GetTranslation(commons.I18n.AuthSuccess),
+		Message: commons.GetTranslation(commons.I18n.AuthCloseWindow),
+	}
+	status := http.StatusOK
+	if authErr != nil {
+		page.Title = commons.GetTranslation(commons.I18n.AuthFailed)
+		page.Message = authErr.Error()
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := authPage.Execute(w, page); err != nil {
+		commons.GetLogger().Println(err)
+	}
+}
+
+// openAuthURLInBrowser opens the authentication URL in the default web browser,
+// using the command that matches the operating system.
+func openAuthURLInBrowser(authenticationUrl string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", authenticationUrl)
+	case "darwin":
+		cmd = exec.Command("open", authenticationUrl)
+	default:
+		cmd = exec.Command("xdg-open", authenticationUrl)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open the browser: %w", err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// fetchToken exchanges the authorization code for an OAuth2 token, using the client credentials of the profile.
+// It sends a POST request to the Patreon API including the PKCE verifier.
+// The token is then parsed from the response and returned as an oauth2.Token,
+// expiring after the number of seconds reported in expires_in.
+func (a *authRequest) fetchToken(ctx context.Context, code string) (*oauth2.Token, error) {
+	data := url.Values{
+		"code":          {code},
+		"grant_type":    {"authorization_code"},
+		"client_id":     {a.profile.setting(commons.ClientId)},
+		"client_secret": {a.profile.setting(commons.ClientSecret)},
+		"redirect_uri":  {a.redirectURI},
+		"code_verifier": {a.verifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, patreon.AccessTokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	var respOAuthToken AccessTokenResponse
+	if err := json.Unmarshal(body, &respOAuthToken); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  respOAuthToken.AccessToken,
+		RefreshToken: respOAuthToken.RefreshToken,
+		TokenType:    respOAuthToken.TokenType,
+	}
+	if respOAuthToken.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(respOAuthToken.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// configuredRedirectURI returns the redirect URI set in the profile, or commons.RedirectURI if none is set.
+func configuredRedirectURI(profile Profile) string {
+	if redirectURI := strings.TrimSpace(profile.setting(commons.CallbackURI)); redirectURI != "" {
+		return redirectURI
+	}
+	return commons.RedirectURI
+}
+
+// isLoopback reports whether the host name refers to the local machine.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// randomString returns a URL safe random string, used for the state and the PKCE verifier.
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		commons.GetLogger().Fatalf("failed to generate random string: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"pick-a-bro/internal/commons"
+)
+
+// Campaign is a Patreon campaign the members were fetched from, named after the campaign name of its profile.
+type Campaign struct {
+	ID   string
+	Name string
+}
+
+// GetCampaignChances returns the chances set for the supporters of the campaign with the given ID, 1 if none is set.
+func GetCampaignChances(campaignID string) float64 {
+	return commons.GetPreferences().FloatWithFallback(commons.CampaignChancesPrefix+campaignID, 1)
+}
+
+// SetCampaignChances stores the chances of the supporters of the campaign under its ID.
+func SetCampaignChances(campaignID string, chances float64) {
+	commons.GetPreferences().SetFloat(commons.CampaignChancesPrefix+campaignID, chances)
+}
+
+// GetMemberCampaignChances returns the chances of the member under the chances by campaign rule:
+// the sum of the chances of every campaign the member supports, so a person supporting two campaigns
+// gets the chances of both. Members of files written before campaigns were tracked get 1 chance.
+func GetMemberCampaignChances(member PatreonMember) float64 {
+	if len(member.Campaigns) == 0 {
+		if member.CampaignID == "" {
+			return 1
+		}
+		return GetCampaignChances(member.CampaignID)
+	}
+
+	chances := 0.0
+	for _, campaignID := range member.Campaigns {
+		chances += GetCampaignChances(campaignID)
+	}
+	return chances
+}
+
+// PersonKey returns the key a person is recognised by within their source: the Patreon user ID, shared by the members
+// of several campaigns, else the member ID, else the name. Member IDs differ between campaigns, so such members are never merged.
+// The campaigns are merged and the pool of the draw is built with this key, so both agree on who is the same person.
+func PersonKey(member PatreonMember) string {
+	switch {
+	case member.UserID != "":
+		return member.Source + "|user:" + member.UserID
+	case member.ID != "":
+		return member.Source + "|id:" + member.ID
+	}
+	return member.Source + "|name:" + member.FullName
+}
+
+// mergeCampaigns merges the progress of the fetch of every campaign into one list of members and one tier catalog.
+// The tiers and the members are tagged with their campaign. A person supporting several campaigns,
+// recognised by the Patreon user ID, is kept once, with the entries of the campaign where their tier is the highest;
+// on equal amounts the first campaign wins. Campaigns lists every campaign each kept member supports.
+// It also returns the number of people found in several campaigns.
+func mergeCampaigns(campaigns []Campaign, progresses []*fetchProgress) ([]PatreonMember, []SkippedMember, *TierCatalog, int) {
+	catalog := newTierCatalog()
+	catalog.Campaigns = campaigns
+	skipped := []SkippedMember{}
+	for i, progress := range progresses {
+		for _, tier := range progress.Tiers.Tiers {
+			if tier.ID != commons.NoTierID {
+				tier.CampaignID = campaigns[i].ID
+			}
+			catalog.add(tier)
+		}
+		skipped = append(skipped, progress.Skipped...)
+	}
+
+	// For every person, the campaigns they support in campaign order, and the campaign of their highest tier
+	supported := map[string][]string{}
+	best := map[string]PatreonMember{}
+	for i, progress := range progresses {
+		for j := range progress.Members {
+			member := &progress.Members[j]
+			member.CampaignID = campaigns[i].ID
+			key := PersonKey(*member)
+			if ids := supported[key]; len(ids) == 0 || ids[len(ids)-1] != member.CampaignID {
+				supported[key] = append(ids, member.CampaignID)
+			}
+			if existing, found := best[key]; !found || compareTiers(memberTier(*member, catalog), memberTier(existing, catalog)) > 0 {
+				best[key] = *member
+			}
+		}
+	}
+
+	members := []PatreonMember{}
+	shared := 0
+	for _, progress := range progresses {
+		for _, member := range progress.Members {
+			key := PersonKey(member)
+			if best[key].CampaignID != member.CampaignID {
+				continue
+			}
+			member.Campaigns = supported[key]
+			members = append(members, member)
+		}
+	}
+	for key, ids := range supported {
+		if len(ids) > 1 {
+			shared++
+			commons.GetLogger().Printf("%s supports %d campaigns, counted once", best[key].FullName, len(ids))
+		}
+	}
+
+	catalog.arrange()
+	return members, skipped, catalog, shared
+}
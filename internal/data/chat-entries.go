@@ -0,0 +1,82 @@
+package data
+
+import (
+	"pick-a-bro/internal/commons"
+	"strings"
+)
+
+// DefaultEntryKeyword is the chat message viewers enter the draw with, unless the source sets another one.
+const DefaultEntryKeyword = "!enter"
+
+// chatEntries collects the viewers entering the draw from a live chat. Every viewer enters once,
+// recognised by their ID on the platform, and the viewers are kept in the order they entered.
+type chatEntries struct {
+	keyword  string
+	campaign Campaign
+	tier     Tier
+	seen     map[string]bool
+	members  []PatreonMember
+	onEntry  func(name string, count int)
+}
+
+// newChatEntries creates the entries of a chat, tagged with the given campaign. All viewers get a single tier named after the campaign.
+// An empty keyword is replaced by DefaultEntryKeyword.
+func newChatEntries(campaign Campaign, keyword string, onEntry func(name string, count int)) *chatEntries {
+	if strings.TrimSpace(keyword) == "" {
+		keyword = DefaultEntryKeyword
+	}
+	return &chatEntries{
+		keyword:  strings.TrimSpace(keyword),
+		campaign: campaign,
+		tier:     Tier{ID: campaign.ID + ":chat", CampaignID: campaign.ID, Title: campaign.Name, Published: true},
+		seen:     map[string]bool{},
+		members:  []PatreonMember{},
+		onEntry:  onEntry,
+	}
+}
+
+// matches reports whether the chat message enters the draw: its first word is the keyword, in any case.
+func (entries *chatEntries) matches(message string) bool {
+	words := strings.Fields(message)
+	return len(words) > 0 && strings.EqualFold(words[0], entries.keyword)
+}
+
+// add enters the viewer with the given ID and name, unless they already entered, and reports the new entry to onEntry.
+func (entries *chatEntries) add(id string, name string) {
+	if id == "" || entries.seen[id] {
+		return
+	}
+	if name == "" {
+		name = id
+	}
+	entries.seen[id] = true
+	entries.members = append(entries.members, PatreonMember{
+		FullName:   name,
+		ID:         entries.campaign.ID + ":" + id,
+		Tier:       entries.tier.Title,
+		TierID:     entries.tier.ID,
+		CampaignID: entries.campaign.ID,
+		Campaigns:  []string{entries.campaign.ID},
+	})
+	if entries.onEntry != nil {
+		entries.onEntry(name, len(entries.members))
+	}
+}
+
+// store writes the collected viewers as the list of the source, see storeSourceList, and adds the source to the sources of the draw.
+// An empty list is stored if no viewer entered, so the viewers of the previous entry window are not drawn again;
+// the source is then not added to the sources of the draw.
+func (entries *chatEntries) store(sourceID string, membersFileName string, tiersFileName string) *FetchResult {
+	catalog := newTierCatalog()
+	catalog.Campaigns = []Campaign{entries.campaign}
+	catalog.add(entries.tier)
+	catalog.arrange()
+
+	result := &FetchResult{Members: entries.members, Skipped: []SkippedMember{}, Tiers: catalog.Tiers, Campaigns: catalog.Campaigns}
+	result.Diff = storeSourceList(sourceID, membersFileName, tiersFileName, result.Members, catalog)
+	if len(result.Members) > 0 {
+		SelectSource(sourceID)
+	}
+	commons.GetLogger().Printf("%d viewers of %s entered the draw", len(result.Members), entries.campaign.Name)
+	return result
+}
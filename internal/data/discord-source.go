@@ -0,0 +1,407 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"pick-a-bro/internal/commons"
+	"strings"
+)
+
+// DiscordSourceID is the ID of the source of the members of a Discord server.
+const DiscordSourceID = "discord"
+
+// discordAPI is the Discord REST API, used unless another URL is set, e.g. a local mock for testing.
+const discordAPI = "https://discord.com/api/v10"
+
+// Page sizes of the Discord list endpoints
+const (
+	discordMembersPageSize   = 1000
+	discordReactionsPageSize = 100
+)
+
+// Settings of the Discord source, see SourceSettingKey
+const (
+	discordTokenSetting   = "token"
+	discordServerSetting  = "server"
+	discordRolesSetting   = "roles"
+	discordMessageSetting = "message"
+	discordEmojiSetting   = "emoji"
+	discordAPISetting     = "api"
+)
+
+var ErrNoDiscordToken = errors.New("no Discord bot token set")
+var ErrNoDiscordServer = errors.New("no Discord server set")
+var ErrNoDiscordRoles = errors.New("none of the Discord roles set was found on the server")
+
+// discordGuild is a Discord server.
+type discordGuild struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// discordRole is a role of a Discord server. The position orders the roles, the highest role has the highest position.
+type discordRole struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
+
+// discordUser is a Discord account.
+type discordUser struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Bot        bool   `json:"bot"`
+}
+
+// discordMember is a member of a Discord server, with their nickname on the server and the IDs of their roles.
+type discordMember struct {
+	User  discordUser `json:"user"`
+	Nick  string      `json:"nick"`
+	Roles []string    `json:"roles"`
+}
+
+// discordMessage is the part of a Discord message its reactions are read from.
+type discordMessage struct {
+	Reactions []struct {
+		Emoji struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"emoji"`
+	} `json:"reactions"`
+}
+
+// discordSource provides the members of a Discord server holding one of the given roles, or the users who reacted
+// to a given message, read with a bot token. Every role becomes a tier, so the chances by tier apply to the roles.
+type discordSource struct{}
+
+func (discordSource) ID() string {
+	return DiscordSourceID
+}
+
+func (discordSource) Name() string {
+	return "Discord"
+}
+
+func (discordSource) Capabilities() SourceCapabilities {
+	return SourceCapabilities{Refresh: true, Tiers: true}
+}
+
+func (discordSource) ConfigFields() []SourceConfigField {
+	return []SourceConfigField{
+		{Key: discordTokenSetting, Label: commons.GetTranslation(commons.I18n.DiscordToken), Secret: true},
+		{Key: discordServerSetting, Label: commons.GetTranslation(commons.I18n.DiscordServer)},
+		{Key: discordRolesSetting, Label: commons.GetTranslation(commons.I18n.DiscordRoles), Placeholder: "Supporter, Moderator"},
+		{Key: discordMessageSetting, Label: commons.GetTranslation(commons.I18n.DiscordMessage), Placeholder: "https://discord.com/channels/"},
+		{Key: discordEmojiSetting, Label: commons.GetTranslation(commons.I18n.DiscordEmoji)},
+		{Key: discordAPISetting, Label: commons.GetTranslation(commons.I18n.DiscordAPI), Placeholder: discordAPI},
+	}
+}
+
+// List reads the members and the roles stored by the last refresh.
+func (discordSource) List() (*MembersList, error) {
+	return readStoredList(commons.StructuredData.DiscordMembersFileName, commons.StructuredData.DiscordTiersFileName)
+}
+
+// Refresh reads the members of the server and writes them with their roles to the local files.
+// If a message is set, the users who reacted to it with the given emoji, or with any emoji if none is set, take part;
+// with roles set too, only those holding one of the roles. Otherwise the members holding one of the roles take part.
+// Every member gets the highest of their roles as tier; reactors without a role set go to the "No tier" bucket.
+// Bots are skipped. Every page received is reported to onEvent, which may be nil.
+func (discordSource) Refresh(ctx context.Context, onEvent func(FetchEvent)) (*FetchResult, error) {
+	client := &discordClient{
+		api:        strings.TrimSuffix(sourceSetting(DiscordSourceID, discordAPISetting), "/"),
+		token:      sourceSetting(DiscordSourceID, discordTokenSetting),
+		httpClient: &http.Client{Transport: newRetryTransport(http.DefaultTransport)},
+		report:     reporter(onEvent),
+	}
+	if client.api == "" {
+		client.api = discordAPI
+	}
+	if client.token == "" {
+		return nil, ErrNoDiscordToken
+	}
+	guildID, channelID, messageID := discordMessageRef(sourceSetting(DiscordSourceID, discordMessageSetting))
+	if server := sourceSetting(DiscordSourceID, discordServerSetting); server != "" {
+		guildID = server
+	}
+	if guildID == "" {
+		return nil, ErrNoDiscordServer
+	}
+
+	guild := discordGuild{}
+	if err := client.get(ctx, "guilds/"+guildID, nil, &guild); err != nil {
+		return nil, err
+	}
+	client.campaign = Campaign{ID: DiscordSourceID, Name: guild.Name}
+	roles := []discordRole{}
+	if err := client.get(ctx, "guilds/"+guildID+"/roles", nil, &roles); err != nil {
+		return nil, err
+	}
+	rolesSetting := sourceSetting(DiscordSourceID, discordRolesSetting)
+	selectedRoles := selectDiscordRoles(roles, rolesSetting)
+	if len(selectedRoles) == 0 && (messageID == "" || rolesSetting != "") {
+		return nil, ErrNoDiscordRoles
+	}
+
+	catalog := newTierCatalog()
+	catalog.Campaigns = []Campaign{client.campaign}
+	result := &FetchResult{Members: []PatreonMember{}, Skipped: []SkippedMember{}, Campaigns: catalog.Campaigns}
+
+	var members []discordMember
+	if len(selectedRoles) > 0 {
+		var err error
+		if members, err = client.guildMembers(ctx, guildID); err != nil {
+			return nil, err
+		}
+	}
+
+	if messageID == "" {
+		for _, member := range members {
+			if role, found := highestDiscordRole(member, selectedRoles); found {
+				addDiscordMember(result, catalog, member, role)
+			}
+		}
+	} else {
+		reactors, err := client.reactors(ctx, channelID, messageID, sourceSetting(DiscordSourceID, discordEmojiSetting))
+		if err != nil {
+			return nil, err
+		}
+		byUserID := map[string]discordMember{}
+		for _, member := range members {
+			byUserID[member.User.ID] = member
+		}
+		for _, user := range reactors {
+			member, found := byUserID[user.ID]
+			if !found {
+				member = discordMember{User: user}
+			}
+			role, hasRole := highestDiscordRole(member, selectedRoles)
+			if len(selectedRoles) > 0 && !hasRole {
+				result.Skipped = append(result.Skipped, SkippedMember{FullName: discordName(member), Reason: "none of the roles set"})
+				continue
+			}
+			addDiscordMember(result, catalog, member, role)
+		}
+	}
+
+	catalog.arrange()
+	result.Tiers = catalog.Tiers
+	result.Pages = client.pages
+	client.report(FetchEvent{Step: FetchStepWrite, Members: len(result.Members)})
+	result.Diff = storeSourceList(DiscordSourceID, commons.StructuredData.DiscordMembersFileName, commons.StructuredData.DiscordTiersFileName, result.Members, catalog)
+
+	commons.GetLogger().Printf("Fetched %d members of the Discord server %s in %d pages, skipped %d", len(result.Members), guild.Name, result.Pages, len(result.Skipped))
+	for _, skipped := range result.Skipped {
+		commons.GetLogger().Printf("Skipped %s: %s", skipped.FullName, skipped.Reason)
+	}
+	return result, nil
+}
+
+// addDiscordMember adds the member to the result with the role as tier, the "No tier" bucket if the role is empty.
+// Bots are skipped.
+func addDiscordMember(result *FetchResult, catalog *TierCatalog, member discordMember, role discordRole) {
+	if member.User.Bot {
+		result.Skipped = append(result.Skipped, SkippedMember{FullName: discordName(member), Reason: "bot"})
+		return
+	}
+
+	tier := Tier{ID: commons.NoTierID, Title: GetNoTierName(), Published: true}
+	if role.ID != "" {
+		tier = Tier{ID: DiscordSourceID + ":" + role.ID, CampaignID: DiscordSourceID, Title: role.Name, Rank: role.Position, Published: true}
+	}
+	catalog.add(tier)
+	result.Members = append(result.Members, PatreonMember{
+		FullName:   discordName(member),
+		Tier:       tier.Title,
+		TierID:     tier.ID,
+		CampaignID: DiscordSourceID,
+		Campaigns:  []string{DiscordSourceID},
+		ID:         DiscordSourceID + ":" + member.User.ID,
+		UserID:     member.User.ID,
+	})
+}
+
+// selectDiscordRoles returns the roles of the server named in the comma separated setting, by name without case or by ID.
+// Names that match no role are logged and left out.
+func selectDiscordRoles(roles []discordRole, setting string) map[string]discordRole {
+	selected := map[string]discordRole{}
+	for _, name := range strings.Split(setting, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, role := range roles {
+			if role.ID == name || strings.EqualFold(role.Name, name) {
+				selected[role.ID] = role
+				found = true
+			}
+		}
+		if !found {
+			commons.GetLogger().Printf("Discord role %s not found", name)
+		}
+	}
+	return selected
+}
+
+// highestDiscordRole returns the selected role of the member with the highest position.
+// The role position is the rank of its tier, so higher roles are ordered and compared as higher tiers.
+func highestDiscordRole(member discordMember, selectedRoles map[string]discordRole) (discordRole, bool) {
+	highest, found := discordRole{}, false
+	for _, id := range member.Roles {
+		if role, ok := selectedRoles[id]; ok && (!found || role.Position > highest.Position) {
+			highest, found = role, true
+		}
+	}
+	return highest, found
+}
+
+// discordName returns the name the member is shown with: their nickname on the server, their display name or their username.
+func discordName(member discordMember) string {
+	for _, name := range []string{member.Nick, member.User.GlobalName, member.User.Username} {
+		if name != "" {
+			return name
+		}
+	}
+	return member.User.ID
+}
+
+// discordMessageRef returns the server, channel and message IDs of a message link, as copied in Discord
+// (https://discord.com/channels/server/channel/message). A "channel/message" pair gives no server.
+func discordMessageRef(link string) (string, string, string) {
+	parts := strings.Split(strings.Trim(link, "/ "), "/")
+	if len(parts) >= 4 && parts[len(parts)-4] == "channels" {
+		return parts[len(parts)-3], parts[len(parts)-2], parts[len(parts)-1]
+	}
+	if len(parts) == 2 {
+		return "", parts[0], parts[1]
+	}
+	return "", "", ""
+}
+
+// discordClient sends the requests of the Discord REST API with a bot token and counts the pages received.
+type discordClient struct {
+	api        string
+	token      string
+	httpClient *http.Client
+	report     func(FetchEvent)
+	campaign   Campaign
+	pages      int
+}
+
+// guildMembers returns every member of the server, one page after the other.
+func (c *discordClient) guildMembers(ctx context.Context, guildID string) ([]discordMember, error) {
+	members := []discordMember{}
+	after := ""
+	for {
+		page := []discordMember{}
+		query := url.Values{"limit": {fmt.Sprint(discordMembersPageSize)}}
+		if after != "" {
+			query.Set("after", after)
+		}
+		if err := c.get(ctx, "guilds/"+guildID+"/members", query, &page); err != nil {
+			return nil, err
+		}
+		members = append(members, page...)
+		c.pages++
+		c.report(FetchEvent{Step: FetchStepFetch, Campaign: c.campaign.Name, Page: c.pages, Members: len(members)})
+		if len(page) < discordMembersPageSize {
+			return members, nil
+		}
+		after = page[len(page)-1].User.ID
+	}
+}
+
+// reactors returns the users who reacted to the message with the emoji, or with any of its reactions if the emoji is empty.
+// A user reacting with several emojis is returned once.
+func (c *discordClient) reactors(ctx context.Context, channelID string, messageID string, emoji string) ([]discordUser, error) {
+	emojis := []string{}
+	if emoji != "" {
+		emojis = append(emojis, strings.TrimPrefix(strings.Trim(emoji, "<>:"), "a:"))
+	} else {
+		message := discordMessage{}
+		if err := c.get(ctx, "channels/"+channelID+"/messages/"+messageID, nil, &message); err != nil {
+			return nil, err
+		}
+		for _, reaction := range message.Reactions {
+			if reaction.Emoji.ID != "" {
+				emojis = append(emojis, reaction.Emoji.Name+":"+reaction.Emoji.ID)
+			} else {
+				emojis = append(emojis, reaction.Emoji.Name)
+			}
+		}
+	}
+
+	users := []discordUser{}
+	seen := map[string]bool{}
+	for _, emoji := range emojis {
+		after := ""
+		for {
+			page := []discordUser{}
+			query := url.Values{"limit": {fmt.Sprint(discordReactionsPageSize)}}
+			if after != "" {
+				query.Set("after", after)
+			}
+			if err := c.get(ctx, "channels/"+channelID+"/messages/"+messageID+"/reactions/"+url.PathEscape(emoji), query, &page); err != nil {
+				return nil, err
+			}
+			for _, user := range page {
+				if !seen[user.ID] {
+					seen[user.ID] = true
+					users = append(users, user)
+				}
+			}
+			c.pages++
+			c.report(FetchEvent{Step: FetchStepFetch, Campaign: c.campaign.Name, Page: c.pages, Members: len(users)})
+			if len(page) < discordReactionsPageSize {
+				break
+			}
+			after = page[len(page)-1].ID
+		}
+	}
+	return users, nil
+}
+
+// get sends a GET request to the endpoint of the API and decodes the response into target.
+// An error response is returned as an error with the message of the API.
+func (c *discordClient) get(ctx context.Context, endpoint string, query url.Values, target any) error {
+	requestURL := fmt.Sprintf("%s/%s", c.api, endpoint)
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Discord request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read Discord response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiError) == nil && apiError.Message != "" {
+			return fmt.Errorf("Discord request failed with status %d: %s", resp.StatusCode, apiError.Message)
+		}
+		return fmt.Errorf("Discord request failed with status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("failed to parse Discord response: %w", err)
+	}
+	return nil
+}
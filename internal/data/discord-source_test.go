@@ -0,0 +1,198 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"pick-a-bro/internal/commons"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// discordFillers is the number of members padding the first page of members to a full page, so a second page is asked for.
+const discordFillers = discordMembersPageSize - 4
+
+// newDiscordServer starts a local Discord API serving the recorded responses of the server g1 and points the settings
+// of the Discord source to it. The first page of members is padded with members holding no role taking part.
+func newDiscordServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bot test-token" {
+			t.Errorf("request with authorization %q", auth)
+		}
+		switch r.URL.Path {
+		case "/guilds/g1":
+			w.Write(readFixture(t, "discord_guild.json"))
+		case "/guilds/g1/roles":
+			w.Write(readFixture(t, "discord_roles.json"))
+		case "/guilds/g1/members":
+			if limit := r.URL.Query().Get("limit"); limit != fmt.Sprint(discordMembersPageSize) {
+				t.Errorf("members requested by %s", limit)
+			}
+			switch after := r.URL.Query().Get("after"); after {
+			case "":
+				members := []discordMember{}
+				json.Unmarshal(readFixture(t, "discord_members_1.json"), &members)
+				for i := 0; i < discordFillers; i++ {
+					members = append(members, discordMember{User: discordUser{ID: fmt.Sprintf("f%04d", i), Username: "filler"}, Roles: []string{"r3"}})
+				}
+				json.NewEncoder(w).Encode(members)
+			case fmt.Sprintf("f%04d", discordFillers-1):
+				w.Write(readFixture(t, "discord_members_2.json"))
+			default:
+				t.Errorf("members requested after %s", after)
+				w.Write([]byte("[]"))
+			}
+		case "/channels/c1/messages/m1":
+			w.Write(readFixture(t, "discord_message.json"))
+		case "/channels/c1/messages/m1/reactions/👍":
+			w.Write(readFixture(t, "discord_reactions_thumbs.json"))
+		case "/channels/c1/messages/m1/reactions/party:123":
+			w.Write(readFixture(t, "discord_reactions_party.json"))
+		default:
+			t.Errorf("unexpected request of %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	setSourceSettings(DiscordSourceID, map[string]string{discordTokenSetting: "test-token", discordAPISetting: server.URL})
+	return server
+}
+
+// memberTiers returns the tier title of every member by name.
+func memberTiers(members []PatreonMember) map[string]string {
+	tiers := map[string]string{}
+	for _, member := range members {
+		tiers[member.FullName] = member.Tier
+	}
+	return tiers
+}
+
+func TestDiscordMembersByRole(t *testing.T) {
+	setupTestData(t)
+	newDiscordServer(t)
+	setSourceSettings(DiscordSourceID, map[string]string{discordServerSetting: "g1", discordRolesSetting: "supporter, r2, Unknown"})
+
+	events := []FetchEvent{}
+	result, err := discordSource{}.Refresh(context.Background(), func(event FetchEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The bot is skipped, Bobby gets the highest of his roles and Dave comes from the second page
+	want := map[string]string{"Ali": "Supporter", "Bobby": "Moderator", "dave": "Moderator"}
+	if tiers := memberTiers(result.Members); !reflect.DeepEqual(tiers, want) {
+		t.Errorf("members %v, want %v", tiers, want)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].FullName != "helper" {
+		t.Errorf("skipped %v, want the bot", result.Skipped)
+	}
+	if result.Pages != 2 || len(events) != 3 || events[1].Page != 2 || events[2].Step != FetchStepWrite {
+		t.Errorf("%d pages reported as %v", result.Pages, events)
+	}
+
+	// The roles are ordered by their position, without an amount
+	if len(result.Tiers) != 2 || result.Tiers[0].Title != "Supporter" || result.Tiers[1].Title != "Moderator" {
+		t.Fatalf("tiers %v, want Supporter then Moderator", result.Tiers)
+	}
+	for _, tier := range result.Tiers {
+		if tier.AmountCents != 0 {
+			t.Errorf("tier %s has an amount of %d", tier.Title, tier.AmountCents)
+		}
+	}
+	stored, err := discordSource{}.List()
+	if err != nil || len(stored.PatreonMembers) != 3 || stored.Tiers[1].Rank != 5 {
+		t.Errorf("stored list %v, %v", stored, err)
+	}
+}
+
+func TestDiscordReactors(t *testing.T) {
+	setupTestData(t)
+	newDiscordServer(t)
+	setSourceSettings(DiscordSourceID, map[string]string{discordMessageSetting: "https://discord.com/channels/g1/c1/m1"})
+
+	result, err := discordSource{}.Refresh(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every reactor takes part once, whatever the emojis, in the "No tier" bucket; the bot is skipped
+	if names, want := memberNames(result.Members), []string{"Alice", "Bobby", "erin"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("reactors %v, want %v", names, want)
+	}
+	for _, member := range result.Members {
+		if member.TierID != commons.NoTierID {
+			t.Errorf("%s has the tier %s, want no tier", member.FullName, member.Tier)
+		}
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].FullName != "giveaway-bot" {
+		t.Errorf("skipped %v, want the bot", result.Skipped)
+	}
+}
+
+func TestDiscordReactorsWithRoles(t *testing.T) {
+	setupTestData(t)
+	newDiscordServer(t)
+	setSourceSettings(DiscordSourceID, map[string]string{
+		discordMessageSetting: "https://discord.com/channels/g1/c1/m1",
+		discordEmojiSetting:   "👍",
+		discordRolesSetting:   "Moderator",
+	})
+
+	result, err := discordSource{}.Refresh(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ali holds no role set and Erin is not a member of the server
+	if tiers, want := memberTiers(result.Members), map[string]string{"Bobby": "Moderator"}; !reflect.DeepEqual(tiers, want) {
+		t.Errorf("reactors %v, want %v", tiers, want)
+	}
+	if len(result.Skipped) != 2 {
+		t.Errorf("skipped %v, want Ali and Erin", result.Skipped)
+	}
+}
+
+func TestDiscordErrors(t *testing.T) {
+	setupTestData(t)
+	if _, err := (discordSource{}).Refresh(context.Background(), nil); !errors.Is(err, ErrNoDiscordToken) {
+		t.Errorf("no token returned %v, want ErrNoDiscordToken", err)
+	}
+
+	newDiscordServer(t)
+	if _, err := (discordSource{}).Refresh(context.Background(), nil); !errors.Is(err, ErrNoDiscordServer) {
+		t.Errorf("no server returned %v, want ErrNoDiscordServer", err)
+	}
+	setSourceSettings(DiscordSourceID, map[string]string{discordServerSetting: "g1", discordRolesSetting: "Unknown"})
+	if _, err := (discordSource{}).Refresh(context.Background(), nil); !errors.Is(err, ErrNoDiscordRoles) {
+		t.Errorf("unknown roles returned %v, want ErrNoDiscordRoles", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write(readFixture(t, "discord_unauthorized.json"))
+	}))
+	defer server.Close()
+	setSourceSettings(DiscordSourceID, map[string]string{discordAPISetting: server.URL})
+	if _, err := (discordSource{}).Refresh(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "status 401: 401: Unauthorized") {
+		t.Errorf("error response returned %v", err)
+	}
+}
+
+func TestDiscordMessageRef(t *testing.T) {
+	for link, want := range map[string][3]string{
+		"https://discord.com/channels/g1/c1/m1": {"g1", "c1", "m1"},
+		"c1/m1":                                 {"", "c1", "m1"},
+		"m1":                                    {"", "", ""},
+	} {
+		if guild, channel, message := discordMessageRef(link); [3]string{guild, channel, message} != want {
+			t.Errorf("discordMessageRef(%q) = %v %v %v, want %v", link, guild, channel, message, want)
+		}
+	}
+}
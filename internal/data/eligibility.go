@@ -0,0 +1,48 @@
+package data
+
+import (
+	"fmt"
+	"pick-a-bro/internal/commons"
+
+	"github.com/austinbspencer/patreon-go-wrapper"
+)
+
+// eligibilityFilter holds the patron statuses and the last charge statuses a member needs to take part in the draw.
+type eligibilityFilter struct {
+	patronStatuses map[string]bool
+	chargeStatuses map[string]bool
+}
+
+// loadEligibilityFilter builds the eligibility filter from the statuses allowed in the preferences.
+// Without stored settings only active patrons whose last charge is paid are eligible.
+func loadEligibilityFilter() eligibilityFilter {
+	preferences := commons.GetPreferences()
+	return eligibilityFilter{
+		patronStatuses: toSet(preferences.StringListWithFallback(commons.AllowedPatronStatuses, commons.DefaultPatronStatuses)),
+		chargeStatuses: toSet(preferences.StringListWithFallback(commons.AllowedChargeStatuses, commons.DefaultChargeStatuses)),
+	}
+}
+
+// isEligible reports whether both the patron status and the last charge status of the member are allowed.
+func (f eligibilityFilter) isEligible(attributes patreon.MemberAttributes) bool {
+	return f.patronStatuses[attributes.PatronStatus] && f.chargeStatuses[attributes.LastChargeStatus]
+}
+
+// ineligibleReason returns why the member is not eligible, empty if they are.
+func (f eligibilityFilter) ineligibleReason(attributes patreon.MemberAttributes) string {
+	switch {
+	case !f.patronStatuses[attributes.PatronStatus]:
+		return fmt.Sprintf("patron status %q not allowed", attributes.PatronStatus)
+	case !f.chargeStatuses[attributes.LastChargeStatus]:
+		return fmt.Sprintf("last charge status %q not allowed", attributes.LastChargeStatus)
+	}
+	return ""
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
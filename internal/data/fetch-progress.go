@@ -0,0 +1,76 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"pick-a-bro/internal/commons"
+)
+
+// fetchProgress is the state of the members fetch of a campaign.
+// When a fetch stops before the last page of a campaign, the progress of every campaign is written
+// to the fetch progress file, so the next fetch continues from the page after the last one received
+// instead of starting over, and skips the campaigns that were already Done.
+type fetchProgress struct {
+	CampaignID string
+	Cursor     string
+	Pages      int
+	Done       bool
+	Members    []PatreonMember
+	Skipped    []SkippedMember
+	Tiers      *TierCatalog
+}
+
+// FetchInterruptedError is returned when the members fetch of a campaign fails after some pages were received.
+// The pages received so far are kept in the fetch progress file.
+type FetchInterruptedError struct {
+	Campaign string
+	Pages    int
+	Members  int
+	Err      error
+}
+
+func (e *FetchInterruptedError) Error() string {
+	return fmt.Sprintf("fetch of %s interrupted after %d pages and %d members: %v", e.Campaign, e.Pages, e.Members, e.Err)
+}
+
+func (e *FetchInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// fetchProgressFilePath returns the path of the fetch progress file in the data directory.
+func fetchProgressFilePath() string {
+	return commons.GetDataPath(commons.StructuredData.FetchProgressFileName)
+}
+
+// loadFetchProgress reads the progress of an interrupted fetch, by campaign ID.
+// It returns an empty map if there is no progress file. A file that can not be read,
+// e.g. one written by a version that fetched a single campaign, is removed.
+func loadFetchProgress() map[string]*fetchProgress {
+	progresses := map[string]*fetchProgress{}
+	if _, err := os.Stat(fetchProgressFilePath()); err != nil {
+		return progresses
+	}
+
+	if !readAndUnmarshal(fetchProgressFilePath(), &progresses) {
+		clearFetchProgress()
+		return map[string]*fetchProgress{}
+	}
+	for campaignID, progress := range progresses {
+		if progress == nil || progress.Tiers == nil {
+			delete(progresses, campaignID)
+		}
+	}
+	return progresses
+}
+
+// saveFetchProgress writes the progress of the campaigns of an interrupted fetch to the fetch progress file.
+func saveFetchProgress(progresses map[string]*fetchProgress) {
+	writeToFile(fetchProgressFilePath(), progresses)
+}
+
+// clearFetchProgress removes the fetch progress file, if any.
+func clearFetchProgress() {
+	if err := os.Remove(fetchProgressFilePath()); err != nil && !os.IsNotExist(err) {
+		commons.GetLogger().Print(err)
+	}
+}
@@ -3,12 +3,13 @@ package data
 import (
 	"log"
 	"os"
+	"pick-a-bro/internal/commons"
 )
 
 // GetLogs retrieves the contents of the log file and returns it as a string.
 // If an error occurs while reading the file, it returns an empty string and the error.
 func GetLogs() (string, error) {
-	data, err := os.ReadFile("log.txt")
+	data, err := os.ReadFile(commons.GetDataPath(commons.LogFileName))
 	if err != nil {
 		log.Fatal(err)
 		return "", err
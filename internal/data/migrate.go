@@ -0,0 +1,109 @@
+package data
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"pick-a-bro/internal/commons"
+	"strings"
+)
+
+// MigrateWorkingDirFiles moves the files that older versions wrote to the working directory into the data directory.
+// It runs once, on the first start with a data directory: afterwards the commons.DataMigrated preference is set
+// and later starts skip it. A file that already exists in the data directory is left in the working directory.
+// It runs before the log file is opened, so instead of logging, it returns the names of the moved files and the errors met.
+func MigrateWorkingDirFiles() ([]string, []error) {
+	preferences := commons.GetPreferences()
+	if preferences.Bool(commons.DataMigrated) {
+		return nil, nil
+	}
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return nil, []error{err}
+	}
+	dataDir, err := filepath.Abs(commons.GetDataDir())
+	if err != nil {
+		return nil, []error{err}
+	}
+	if dataDir == workingDir {
+		preferences.SetBool(commons.DataMigrated, true)
+		return nil, nil
+	}
+
+	names := []string{
+		commons.LogFileName,
+		commons.StructuredData.RealDataFileName,
+		commons.StructuredData.TestDataFileName,
+		commons.StructuredData.RealTiersFileName,
+		commons.StructuredData.TestTiersFileName,
+		commons.StructuredData.WinnersFileName,
+		commons.StructuredData.FetchProgressFileName,
+		commons.StructuredData.SnapshotsDir,
+		commons.StructuredData.TestSnapshotsDir,
+	}
+	for _, membersFileName := range []string{commons.StructuredData.RealDataFileName, commons.StructuredData.TestDataFileName} {
+		diffs, _ := filepath.Glob(strings.TrimSuffix(membersFileName, ".json") + "_diff_*.json")
+		names = append(names, diffs...)
+	}
+
+	moved := []string{}
+	errs := []error{}
+	for _, name := range names {
+		source := filepath.Join(workingDir, name)
+		target := filepath.Join(dataDir, name)
+		if _, err := os.Stat(source); err != nil {
+			continue
+		}
+		if _, err := os.Stat(target); err == nil {
+			errs = append(errs, fmt.Errorf("%s not migrated, it already exists in %s", name, dataDir))
+			continue
+		}
+		if err := moveFile(source, target); err != nil {
+			errs = append(errs, fmt.Errorf("%s not migrated: %w", name, err))
+			continue
+		}
+		moved = append(moved, name)
+	}
+
+	preferences.SetBool(commons.DataMigrated, true)
+	return moved, errs
+}
+
+// moveFile moves a file or directory. When renaming fails, e.g. across file systems, a file is copied and then removed.
+func moveFile(source string, target string) error {
+	if err := os.Rename(source, target); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("unable to move directory %s", source)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(target)
+		return err
+	}
+	in.Close()
+	return os.Remove(source)
+}
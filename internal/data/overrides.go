@@ -0,0 +1,198 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"pick-a-bro/internal/commons"
+	"strings"
+	"time"
+)
+
+// Changes of the operator a participant of the draw is marked with, see PatreonMember.Override
+const (
+	OverrideAdded   = "added"
+	OverrideEdited  = "edited"
+	OverrideRemoved = "removed"
+)
+
+// manualIDPrefix starts the ID of the participants added by the operator.
+const manualIDPrefix = "manual:"
+
+// MemberEdit is the tier and the number of entries the operator set for a member of the list.
+type MemberEdit struct {
+	TierID  string
+	Entries int
+}
+
+// DrawOverrides are the changes of the operator to the patreons list, kept in their own file,
+// so the fetched members and their snapshots are never modified. Added holds the participants added by hand
+// and Edited the changes to listed members, by member key.
+type DrawOverrides struct {
+	Added  []PatreonMember
+	Edited map[string]MemberEdit
+}
+
+// drawRemovals holds the keys of the members left out of the draw being prepared. They are not written to the overrides file:
+// they only last until the draw is recorded or aborted, see ClearDrawRemovals.
+var drawRemovals = map[string]bool{}
+
+// overridesFilePath returns the path of the overrides file, a separate one in test mode.
+func overridesFilePath() string {
+	return getFilePath(commons.StructuredData.TestOverridesFileName, commons.StructuredData.OverridesFileName)
+}
+
+// loadOverrides reads the overrides file. A missing or unreadable file gives no overrides.
+func loadOverrides() *DrawOverrides {
+	overrides := &DrawOverrides{}
+	if _, err := os.Stat(overridesFilePath()); err == nil {
+		readAndUnmarshal(overridesFilePath(), overrides)
+	}
+	if overrides.Edited == nil {
+		overrides.Edited = map[string]MemberEdit{}
+	}
+	return overrides
+}
+
+// memberKey returns the key the changes to a member are stored under: the member ID, or the full name
+// for files written before IDs were stored, together with the tier, as a member may have an entry per tier.
+// A participant added by hand has a single entry and is known by its ID alone, so editing its tier keeps its key.
+func memberKey(member PatreonMember) string {
+	if strings.HasPrefix(member.ID, manualIDPrefix) {
+		return member.ID
+	}
+	if member.ID == "" {
+		return fmt.Sprintf("name:%s|%s", member.FullName, member.TierID)
+	}
+	return fmt.Sprintf("%s|%s", member.ID, member.TierID)
+}
+
+// applyOverrides returns the members of the list with the overrides applied: the edited members get their new tier
+// and entries, the participants added by hand are appended and every changed member is marked with its kind of change.
+// Removed members stay in the list, marked as removed, so they can be restored; the draw leaves them out.
+func applyOverrides(members []PatreonMember, overrides *DrawOverrides) []PatreonMember {
+	removed := drawRemovals
+	applied := make([]PatreonMember, 0, len(members)+len(overrides.Added))
+	for _, member := range members {
+		member.overrideKey = memberKey(member)
+		if edit, found := overrides.Edited[member.overrideKey]; found {
+			member.Entries = edit.Entries
+			setMemberTier(&member, edit.TierID)
+			member.Override = OverrideEdited
+		}
+		if removed[member.overrideKey] {
+			member.Override = OverrideRemoved
+		}
+		applied = append(applied, member)
+	}
+
+	for _, member := range overrides.Added {
+		member.overrideKey = memberKey(member)
+		setMemberTier(&member, member.TierID)
+		member.Override = OverrideAdded
+		if removed[member.overrideKey] {
+			member.Override = OverrideRemoved
+		}
+		applied = append(applied, member)
+	}
+	return applied
+}
+
+// setMemberTier sets the tier of the member, adding the "No tier" bucket to the list when it is picked and missing.
+// A tier that is not in the list leaves the member's tier unchanged.
+func setMemberTier(member *PatreonMember, tierID string) {
+	if tierID == commons.NoTierID {
+		if _, found := listTier(commons.NoTierID); !found {
+			list.Tiers = append(list.Tiers, Tier{ID: commons.NoTierID, Title: GetNoTierName(), Published: true, Order: len(list.Tiers),
+				Color: tierColor(len(list.Tiers), commons.NoTierID)})
+			generateColorCodes()
+		}
+	}
+	if tier, found := listTier(tierID); found {
+		member.TierID, member.Tier = tier.ID, tier.Title
+	}
+}
+
+// updateOverrides changes the stored overrides with the given function, writes them and applies them to the loaded list, if any.
+func updateOverrides(change func(overrides *DrawOverrides)) {
+	overrides := loadOverrides()
+	change(overrides)
+	writeToFile(overridesFilePath(), overrides)
+	reapplyOverrides(overrides)
+}
+
+// reapplyOverrides applies the overrides and the removals of the draw to the loaded list, if any.
+func reapplyOverrides(overrides *DrawOverrides) {
+	if list != nil {
+		list.PatreonMembers = applyOverrides(listedMembers, overrides)
+	}
+}
+
+// AddParticipant adds a participant by hand, in the given tier and with the given number of entries.
+func AddParticipant(fullName string, tierID string, entries int) {
+	updateOverrides(func(overrides *DrawOverrides) {
+		overrides.Added = append(overrides.Added, PatreonMember{
+			FullName: fullName,
+			TierID:   tierID,
+			ID:       fmt.Sprintf("%s%d", manualIDPrefix, time.Now().UnixNano()),
+			Entries:  entries,
+		})
+	})
+	commons.GetLogger().Printf("%s added by hand", fullName)
+}
+
+// EditParticipant sets the tier and the number of entries of the participant.
+func EditParticipant(member PatreonMember, tierID string, entries int) {
+	updateOverrides(func(overrides *DrawOverrides) {
+		for i := range overrides.Added {
+			if memberKey(overrides.Added[i]) == member.overrideKey {
+				overrides.Added[i].TierID, overrides.Added[i].Entries = tierID, entries
+				return
+			}
+		}
+		overrides.Edited[member.overrideKey] = MemberEdit{TierID: tierID, Entries: entries}
+	})
+	commons.GetLogger().Printf("%s edited by hand", member.FullName)
+}
+
+// RemoveParticipant leaves the participant out of the next draw.
+func RemoveParticipant(member PatreonMember) {
+	drawRemovals[member.overrideKey] = true
+	reapplyOverrides(loadOverrides())
+}
+
+// RestoreParticipant brings a removed participant back into the draw.
+func RestoreParticipant(member PatreonMember) {
+	delete(drawRemovals, member.overrideKey)
+	reapplyOverrides(loadOverrides())
+}
+
+// ResetOverrides drops every change of the operator, so the draw uses the list as fetched.
+func ResetOverrides() {
+	drawRemovals = map[string]bool{}
+	updateOverrides(func(overrides *DrawOverrides) {
+		*overrides = DrawOverrides{Edited: map[string]MemberEdit{}}
+	})
+	commons.GetLogger().Print("Changes to the patreons list reset")
+}
+
+// ClearDrawRemovals brings back the members removed for the draw once it is recorded or aborted. The other overrides are kept.
+func ClearDrawRemovals() {
+	if len(drawRemovals) == 0 {
+		return
+	}
+	drawRemovals = map[string]bool{}
+	reapplyOverrides(loadOverrides())
+}
+
+// HasOverrides reports whether the operator changed the loaded list.
+func HasOverrides() bool {
+	if list == nil {
+		return false
+	}
+	for _, member := range list.PatreonMembers {
+		if member.Override != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,356 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"pick-a-bro/internal/commons"
+	"strconv"
+	"strings"
+
+	"github.com/austinbspencer/patreon-go-wrapper"
+)
+
+var ErrNoNameColumn = errors.New("no column is mapped to the participant name")
+var ErrEmptyImportFile = errors.New("the file has no participants")
+
+// FileSourceID is the ID of the source of the participants imported from a file.
+const FileSourceID = "file"
+
+// importCampaignID is the campaign the imported participants are tagged with.
+const importCampaignID = "import"
+
+// fileSource provides the participants of the last imported file. Its list can only be replaced by importing another file.
+type fileSource struct{}
+
+func (fileSource) ID() string {
+	return FileSourceID
+}
+
+func (fileSource) Name() string {
+	return commons.GetTranslation(commons.I18n.ImportedParticipants)
+}
+
+func (fileSource) Capabilities() SourceCapabilities {
+	return SourceCapabilities{Tiers: true, Import: true}
+}
+
+func (fileSource) ConfigFields() []SourceConfigField {
+	return nil
+}
+
+// List reads the participants and the tiers stored by the last import.
+func (fileSource) List() (*MembersList, error) {
+	return readStoredList(commons.StructuredData.ImportDataFileName, commons.StructuredData.ImportTiersFileName)
+}
+
+func (fileSource) Refresh(ctx context.Context, onEvent func(FetchEvent)) (*FetchResult, error) {
+	return nil, ErrRefreshNotSupported
+}
+
+// ImportTable is the content of a participants file: the header row and the data rows, without the empty rows.
+type ImportTable struct {
+	FileName string
+	Header   []string
+	Rows     [][]string
+}
+
+// ImportMapping holds the column of every participant field, -1 for a field without a column.
+// The status and pledge columns are only mapped for a Patreon dashboard export, see DetectImportMapping.
+type ImportMapping struct {
+	Name             int
+	Tier             int
+	Entries          int
+	ID               int
+	Email            int
+	PatronStatus     int
+	LastChargeStatus int
+	Pledge           int
+	PatreonExport    bool
+}
+
+// ReadParticipantFile reads a CSV, TSV or XLSX file of participants. The first non-empty row is the header.
+// The delimiter of a CSV file is detected from its header: a comma, a semicolon or a tab.
+func ReadParticipantFile(filePath string) (*ImportTable, error) {
+	var rows [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".xlsx":
+		rows, err = readXLSX(filePath)
+	case ".tsv", ".tab":
+		rows, err = readDelimited(filePath, '\t')
+	default:
+		rows, err = readDelimited(filePath, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	table := &ImportTable{FileName: filepath.Base(filePath), Rows: [][]string{}}
+	for _, row := range rows {
+		if isEmptyRow(row) {
+			continue
+		}
+		if table.Header == nil {
+			table.Header = row
+			continue
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	if len(table.Rows) == 0 {
+		return nil, ErrEmptyImportFile
+	}
+	return table, nil
+}
+
+// readDelimited reads the records of a delimited text file. A zero delimiter is detected from the first line.
+// Rows may have a different number of fields, and a UTF-8 byte order mark is skipped.
+func readDelimited(filePath string, delimiter rune) ([][]string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	if delimiter == 0 {
+		firstLine, _ := bufio.NewReader(bytes.NewReader(content)).ReadString('\n')
+		delimiter = detectDelimiter(firstLine)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows := [][]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, record)
+	}
+}
+
+// detectDelimiter returns the most frequent of the comma, the semicolon and the tab in the line, the comma if none is found.
+func detectDelimiter(line string) rune {
+	delimiter, count := ',', strings.Count(line, ",")
+	for _, candidate := range []rune{';', '\t'} {
+		if n := strings.Count(line, string(candidate)); n > count {
+			delimiter, count = candidate, n
+		}
+	}
+	return delimiter
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// DetectImportMapping guesses the column of every participant field from the header names.
+// A header holding every column of commons.PatreonExportColumns is recognised as a Patreon dashboard export;
+// its status and pledge columns are then mapped too, so only the eligible members are imported.
+func DetectImportMapping(header []string) ImportMapping {
+	mapping := ImportMapping{
+		Name:             findColumn(header, commons.ImportColumnAliases.Name),
+		Tier:             findColumn(header, commons.ImportColumnAliases.Tier),
+		Entries:          findColumn(header, commons.ImportColumnAliases.Entries),
+		ID:               findColumn(header, commons.ImportColumnAliases.ID),
+		Email:            findColumn(header, commons.ImportColumnAliases.Email),
+		PatronStatus:     -1,
+		LastChargeStatus: -1,
+		Pledge:           -1,
+	}
+
+	for _, column := range commons.PatreonExportColumns {
+		if findColumn(header, []string{column}) < 0 {
+			return mapping
+		}
+	}
+	mapping.PatreonExport = true
+	mapping.PatronStatus = findColumn(header, []string{"patron status"})
+	mapping.LastChargeStatus = findColumn(header, []string{"last charge status"})
+	mapping.Pledge = findColumn(header, []string{"pledge amount"})
+	return mapping
+}
+
+// findColumn returns the index of the first header matching one of the names, ignoring case and surrounding spaces, or -1.
+func findColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// ImportParticipantsToLocalStorage converts the participants of the table with the given mapping and writes them
+// to the files of the file source, replacing the previous import, and adds the file source to the sources of the draw.
+// No snapshot is kept of an import, see SourceCapabilities.Snapshots.
+func ImportParticipantsToLocalStorage(table *ImportTable, mapping ImportMapping) (*FetchResult, error) {
+	result, catalog, err := importParticipants(table, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Diff = storeSourceList(FileSourceID, commons.StructuredData.ImportDataFileName, commons.StructuredData.ImportTiersFileName, result.Members, catalog)
+	SelectSource(FileSourceID)
+
+	commons.GetLogger().Printf("Imported %d participants from %s, skipped %d", len(result.Members), table.FileName, len(result.Skipped))
+	for _, skipped := range result.Skipped {
+		commons.GetLogger().Printf("Skipped %s: %s", skipped.FullName, skipped.Reason)
+	}
+	return result, nil
+}
+
+// importParticipants converts the rows of the table to members of a single campaign named after the file.
+// The ID column of a Patreon export holds the Patreon user ID, so its members are recognised as the fetched ones.
+// Every distinct tier title becomes a tier; rows without a tier go to the "No tier" bucket.
+// Rows without a name, rows of a Patreon export whose statuses are not eligible, and repeated IDs or emails are skipped
+// and listed in the result with the reason.
+// An entries cell that is not a positive number counts as a single entry.
+func importParticipants(table *ImportTable, mapping ImportMapping) (*FetchResult, *TierCatalog, error) {
+	if mapping.Name < 0 {
+		return nil, nil, ErrNoNameColumn
+	}
+
+	campaign := Campaign{ID: importCampaignID, Name: strings.TrimSuffix(table.FileName, filepath.Ext(table.FileName))}
+	catalog := newTierCatalog()
+	catalog.Campaigns = []Campaign{campaign}
+	eligibility := loadEligibilityFilter()
+	result := &FetchResult{Members: []PatreonMember{}, Skipped: []SkippedMember{}, Campaigns: catalog.Campaigns}
+	seen := map[string]int{}
+
+	for i, row := range table.Rows {
+		rowNumber := i + 2
+		member := PatreonMember{
+			FullName:   cell(row, mapping.Name),
+			ID:         cell(row, mapping.ID),
+			Email:      cell(row, mapping.Email),
+			CampaignID: campaign.ID,
+			Campaigns:  []string{campaign.ID},
+		}
+		if member.FullName == "" {
+			result.Skipped = append(result.Skipped, SkippedMember{FullName: fmt.Sprintf("#%d", rowNumber), Reason: "no name"})
+			continue
+		}
+
+		if mapping.PatreonExport {
+			member.PatronStatus = exportPatronStatus(cell(row, mapping.PatronStatus))
+			member.LastChargeStatus = cell(row, mapping.LastChargeStatus)
+			member.CurrentlyEntitledAmountCents = parseCents(cell(row, mapping.Pledge))
+			member.UserID = member.ID
+			if reason := eligibility.ineligibleReason(patreon.MemberAttributes{PatronStatus: member.PatronStatus, LastChargeStatus: member.LastChargeStatus}); reason != "" {
+				result.Skipped = append(result.Skipped, SkippedMember{FullName: member.FullName, Reason: reason})
+				continue
+			}
+		}
+
+		if key := importKey(member); key != "" {
+			if first, found := seen[key]; found {
+				result.Skipped = append(result.Skipped, SkippedMember{FullName: member.FullName, Reason: fmt.Sprintf("duplicate of row %d", first)})
+				continue
+			}
+			seen[key] = rowNumber
+		}
+		if member.ID == "" {
+			member.ID = fmt.Sprintf("%s:%d", importCampaignID, rowNumber)
+		}
+
+		if entries, err := strconv.Atoi(cell(row, mapping.Entries)); err == nil && entries > 0 {
+			member.Entries = entries
+		}
+
+		tier := importTier(catalog, cell(row, mapping.Tier), member.CurrentlyEntitledAmountCents)
+		member.Tier, member.TierID = tier.Title, tier.ID
+		result.Members = append(result.Members, member)
+	}
+
+	if len(result.Members) == 0 {
+		return nil, nil, ErrEmptyImportFile
+	}
+	catalog.arrange()
+	result.Tiers = catalog.Tiers
+	return result, catalog, nil
+}
+
+// importTier returns the tier of the catalog with the given title, adding it if needed.
+// A tier takes the highest pledge of its members as its amount, so the tiers of a Patreon export keep their order.
+func importTier(catalog *TierCatalog, title string, amountCents int) Tier {
+	if title == "" {
+		tier := Tier{ID: commons.NoTierID, Title: GetNoTierName(), Published: true}
+		catalog.add(tier)
+		return tier
+	}
+
+	tier, found := catalog.Get(importCampaignID + ":" + strings.ToLower(title))
+	if !found {
+		tier = Tier{ID: importCampaignID + ":" + strings.ToLower(title), CampaignID: importCampaignID, Title: title, Published: true}
+	}
+	if amountCents > tier.AmountCents {
+		tier.AmountCents = amountCents
+	}
+	catalog.add(tier)
+	return tier
+}
+
+// importKey returns the key duplicate rows are recognised by: the ID, or else the email, empty if the row has neither.
+func importKey(member PatreonMember) string {
+	if member.ID != "" {
+		return "id:" + member.ID
+	}
+	if member.Email != "" {
+		return "email:" + strings.ToLower(member.Email)
+	}
+	return ""
+}
+
+// cell returns the trimmed value of the row in the given column, empty for an unmapped or missing column.
+func cell(row []string, column int) string {
+	if column < 0 || column >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[column])
+}
+
+// exportPatronStatus converts a patron status of the Patreon dashboard export, e.g. "Active patron",
+// to the status reported by the API, e.g. "active_patron".
+func exportPatronStatus(status string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(status)), " ", "_")
+}
+
+// parseCents converts an amount such as "5.00", "$1,250.00", "1,250" or "5,00" to cents, 0 if it is not a number.
+// A comma followed by exactly two digits at the end is the decimal separator, the dots before it separating thousands
+// as in "1.250,00"; any other comma separates thousands.
+func parseCents(amount string) int {
+	amount = strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' {
+			return r
+		}
+		return -1
+	}, amount)
+	if comma := strings.LastIndex(amount, ","); comma > strings.LastIndex(amount, ".") && len(amount)-comma-1 == 2 {
+		amount = strings.ReplaceAll(amount[:comma], ".", "") + "." + amount[comma+1:]
+	}
+	amount = strings.ReplaceAll(amount, ",", "")
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0
+	}
+	return int(value*100 + 0.5)
+}
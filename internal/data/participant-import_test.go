@@ -0,0 +1,306 @@
+package data
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadParticipantFile(t *testing.T) {
+	for _, test := range []struct {
+		file   string
+		header []string
+		rows   [][]string
+	}{
+		{
+			file:   "import_guests.csv",
+			header: []string{"Name", "Tier", "Entries", "Email"},
+			rows: [][]string{
+				{"Alice", "Gold", "3", "alice@example.com"},
+				{"Bob", "", "x", "bob@example.com"},
+				{"", "Silver", "1", "nobody@example.com"},
+				{"Alice Again", "Silver", "2", "ALICE@example.com"},
+				{"Carol, Jr.", "silver", "0", ""},
+			},
+		},
+		{
+			file:   "import_guests_semicolon.csv",
+			header: []string{"Name", "Tier", "Entries"},
+			rows:   [][]string{{"Doe, Jane", "Gold", "2"}, {"Roe, Richard", "Bronze", "1"}},
+		},
+		{
+			file:   "import_guests.tsv",
+			header: []string{"Participant", "Level", "Tickets"},
+			rows:   [][]string{{"Dave", "Gold", "4"}, {"Erin", "", ""}},
+		},
+		{
+			// The first sheet of the workbook is stored in sheet2.xml, and the second column is left empty
+			file:   "import_shared_strings.xlsx",
+			header: []string{"Name", "", "Tier", "Entries"},
+			rows:   [][]string{{"Alice", "", "Gold", "2"}, {"Bob Builder", "", "", "1"}},
+		},
+		{
+			file:   "import_inline_strings.xlsx",
+			header: []string{"Full Name", "Email"},
+			rows:   [][]string{{"Carol", "carol@example.com"}, {"Dave Jones"}},
+		},
+	} {
+		table, err := ReadParticipantFile(filepath.Join("testdata", test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if table.FileName != test.file {
+			t.Errorf("%s: file name %q", test.file, table.FileName)
+		}
+		if !reflect.DeepEqual(table.Header, test.header) {
+			t.Errorf("%s: header %q, want %q", test.file, table.Header, test.header)
+		}
+		if !reflect.DeepEqual(table.Rows, test.rows) {
+			t.Errorf("%s: rows %q, want %q", test.file, table.Rows, test.rows)
+		}
+	}
+}
+
+func TestReadParticipantFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"header_only.csv": "Name,Tier\n\n",
+		"not_a_zip.xlsx":  "Name,Tier\nAlice,Gold\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := ReadParticipantFile(filepath.Join(dir, "header_only.csv")); !errors.Is(err, ErrEmptyImportFile) {
+		t.Errorf("a file without participants returned %v, want ErrEmptyImportFile", err)
+	}
+	if _, err := ReadParticipantFile(filepath.Join(dir, "not_a_zip.xlsx")); err == nil {
+		t.Error("a CSV file named .xlsx was read")
+	}
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	for line, want := range map[string]rune{
+		"Name,Tier,Entries":     ',',
+		"Name;Tier;Entries":     ';',
+		"Name\tTier\tEntries":   '\t',
+		"Name;Tier;Doe, Jane\n": ';',
+		"Name,Tier;Entries":     ',',
+		"Name":                  ',',
+	} {
+		if delimiter := detectDelimiter(line); delimiter != want {
+			t.Errorf("detectDelimiter(%q) = %q, want %q", line, delimiter, want)
+		}
+	}
+}
+
+func TestDetectImportMapping(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		header []string
+		want   ImportMapping
+	}{
+		{
+			name:   "guests",
+			header: []string{"Name", "Tier", "Entries", "Email"},
+			want:   ImportMapping{Name: 0, Tier: 1, Entries: 2, ID: -1, Email: 3, PatronStatus: -1, LastChargeStatus: -1, Pledge: -1},
+		},
+		{
+			name:   "aliases in any case",
+			header: []string{"E-Mail", " FULL NAME ", "Tickets", "Member ID", "Reward"},
+			want:   ImportMapping{Name: 1, Tier: 4, Entries: 2, ID: 3, Email: 0, PatronStatus: -1, LastChargeStatus: -1, Pledge: -1},
+		},
+		{
+			name:   "no name column",
+			header: []string{"Nickname", "Tier"},
+			want:   ImportMapping{Name: -1, Tier: 1, Entries: -1, ID: -1, Email: -1, PatronStatus: -1, LastChargeStatus: -1, Pledge: -1},
+		},
+		{
+			name:   "export without user ID",
+			header: []string{"Name", "Email", "Patron Status", "Pledge Amount", "Tier", "Last Charge Status"},
+			want:   ImportMapping{Name: 0, Tier: 4, Entries: -1, ID: -1, Email: 1, PatronStatus: -1, LastChargeStatus: -1, Pledge: -1},
+		},
+		{
+			name: "Patreon export",
+			header: []string{"Name", "Email", "Twitter", "Patron Status", "Follows You", "Lifetime Amount", "Pledge Amount",
+				"Charge Frequency", "Tier", "Addressee", "Last Charge Date", "Last Charge Status", "User ID"},
+			want: ImportMapping{Name: 0, Tier: 8, Entries: -1, ID: 12, Email: 1, PatronStatus: 3, LastChargeStatus: 11, Pledge: 6, PatreonExport: true},
+		},
+	} {
+		if mapping := DetectImportMapping(test.header); mapping != test.want {
+			t.Errorf("%s: mapping %+v, want %+v", test.name, mapping, test.want)
+		}
+	}
+}
+
+func TestImportParticipants(t *testing.T) {
+	for _, test := range []struct {
+		file    string
+		names   []string
+		ids     []string
+		entries []int
+		tiers   []string
+		skipped []SkippedMember
+	}{
+		{
+			// Repeated emails are told apart in any case, a row without email is never a duplicate,
+			// and an entries cell that is not a positive number counts as a single entry
+			file:    "import_guests.csv",
+			names:   []string{"Alice", "Bob", "Carol, Jr."},
+			ids:     []string{"import:2", "import:3", "import:6"},
+			entries: []int{3, 0, 0},
+			tiers:   []string{"Gold", "No tier", "silver"},
+			skipped: []SkippedMember{{FullName: "#4", Reason: "no name"}, {FullName: "Alice Again", Reason: "duplicate of row 2"}},
+		},
+		{
+			// Only the members allowed by the eligibility settings are imported, the others are listed with the reason
+			file:    "import_patreon_export.csv",
+			names:   []string{"Alice", "Dave", "Erin"},
+			ids:     []string{"1001", "1004", "1005"},
+			entries: []int{0, 0, 0},
+			tiers:   []string{"Gold", "Platinum", "Silver"},
+			skipped: []SkippedMember{
+				{FullName: "Bob", Reason: `patron status "former_patron" not allowed`},
+				{FullName: "Carol", Reason: `last charge status "Declined" not allowed`},
+			},
+		},
+		{
+			file:    "import_shared_strings.xlsx",
+			names:   []string{"Alice", "Bob Builder"},
+			ids:     []string{"import:2", "import:3"},
+			entries: []int{2, 1},
+			tiers:   []string{"Gold", "No tier"},
+			skipped: []SkippedMember{},
+		},
+	} {
+		setupTestData(t)
+		table, err := ReadParticipantFile(filepath.Join("testdata", test.file))
+		if err != nil {
+			t.Fatalf("%s: %v", test.file, err)
+		}
+		result, catalog, err := importParticipants(table, DetectImportMapping(table.Header))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+
+		ids, entries, tiers := []string{}, []int{}, []string{}
+		for _, member := range result.Members {
+			ids = append(ids, member.ID)
+			entries = append(entries, member.Entries)
+			tiers = append(tiers, member.Tier)
+			if tier, found := catalog.Get(member.TierID); !found || tier.Title != member.Tier {
+				t.Errorf("%s: %s in tier %s missing from the catalog", test.file, member.FullName, member.TierID)
+			}
+		}
+		if names := memberNames(result.Members); !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%s: imported %v, want %v", test.file, names, test.names)
+		}
+		if !reflect.DeepEqual(ids, test.ids) {
+			t.Errorf("%s: IDs %v, want %v", test.file, ids, test.ids)
+		}
+		if !reflect.DeepEqual(entries, test.entries) {
+			t.Errorf("%s: entries %v, want %v", test.file, entries, test.entries)
+		}
+		if !reflect.DeepEqual(tiers, test.tiers) {
+			t.Errorf("%s: tiers %v, want %v", test.file, tiers, test.tiers)
+		}
+		if !reflect.DeepEqual(result.Skipped, test.skipped) {
+			t.Errorf("%s: skipped %v, want %v", test.file, result.Skipped, test.skipped)
+		}
+	}
+}
+
+func TestImportPatreonExportTiers(t *testing.T) {
+	setupTestData(t)
+	table, err := ReadParticipantFile(filepath.Join("testdata", "import_patreon_export.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _, err := importParticipants(table, DetectImportMapping(table.Header))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amounts := map[string]int{}
+	titles := []string{}
+	for _, tier := range result.Tiers {
+		amounts[tier.Title] = tier.AmountCents
+		titles = append(titles, tier.Title)
+	}
+	if want := []string{"Silver", "Gold", "Platinum"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("tiers ordered %v, want %v", titles, want)
+	}
+	if want := map[string]int{"Silver": 500, "Gold": 1000, "Platinum": 125000}; !reflect.DeepEqual(amounts, want) {
+		t.Errorf("tier amounts %v, want %v", amounts, want)
+	}
+	if member := result.Members[1]; member.UserID != "1004" || member.PatronStatus != "active_patron" || member.CurrentlyEntitledAmountCents != 125000 {
+		t.Errorf("exported member %+v", member)
+	}
+}
+
+func TestImportParticipantsErrors(t *testing.T) {
+	setupTestData(t)
+	table := &ImportTable{FileName: "guests.csv", Header: []string{"Nickname", "Tier"}, Rows: [][]string{{"Alice", "Gold"}}}
+	if _, _, err := importParticipants(table, DetectImportMapping(table.Header)); !errors.Is(err, ErrNoNameColumn) {
+		t.Errorf("a table without name column returned %v, want ErrNoNameColumn", err)
+	}
+
+	table = &ImportTable{FileName: "guests.csv", Header: []string{"Name", "Tier"}, Rows: [][]string{{"", "Gold"}}}
+	if _, _, err := importParticipants(table, DetectImportMapping(table.Header)); !errors.Is(err, ErrEmptyImportFile) {
+		t.Errorf("a table without named rows returned %v, want ErrEmptyImportFile", err)
+	}
+}
+
+func TestImportParticipantsToLocalStorage(t *testing.T) {
+	setupTestData(t)
+	table, err := ReadParticipantFile(filepath.Join("testdata", "import_guests.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportParticipantsToLocalStorage(table, DetectImportMapping(table.Header)); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := fileSource{}.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := memberNames(stored.PatreonMembers); !reflect.DeepEqual(names, []string{"Dave", "Erin"}) {
+		t.Errorf("stored %v", names)
+	}
+	if source := stored.PatreonMembers[0].Source; source != FileSourceID {
+		t.Errorf("stored with source %q", source)
+	}
+	if ids := GetSelectedSourceIDs(); !reflect.DeepEqual(ids, []string{PatreonSourceID, FileSourceID}) {
+		t.Errorf("sources of the draw %v, want the file added", ids)
+	}
+	if snapshots, _ := os.ReadDir(snapshotsDir()); len(snapshots) != 0 {
+		t.Errorf("the import kept %d snapshots", len(snapshots))
+	}
+}
+
+func TestParseCents(t *testing.T) {
+	for amount, want := range map[string]int{
+		"5":         500,
+		"5.00":      500,
+		"$5.5":      550,
+		"5,00":      500,
+		"€ 12,50":   1250,
+		"1,250":     125000,
+		"$1,250.00": 125000,
+		"1,250,000": 125000000,
+		"1.250,00":  125000,
+		"US$ 0.99":  99,
+		"":          0,
+		"free":      0,
+	} {
+		if cents := parseCents(amount); cents != want {
+			t.Errorf("parseCents(%q) = %d, want %d", amount, cents, want)
+		}
+	}
+}
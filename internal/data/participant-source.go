@@ -0,0 +1,288 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pick-a-bro/internal/commons"
+	"strings"
+)
+
+var ErrNoSource = errors.New("no participant source selected")
+var ErrRefreshNotSupported = errors.New("the participant source can not be refreshed")
+
+// SourceCapabilities describes what a participant source supports.
+// Refresh is set when the source can fetch a new list, Tiers when its participants have tiers,
+// Import when its list is filled from a file, Sample when it only provides made-up participants for the test mode,
+// and Snapshots when every list it stores is kept as a snapshot the draw can be pinned to, see archiveSnapshot.
+type SourceCapabilities struct {
+	Refresh   bool
+	Tiers     bool
+	Import    bool
+	Sample    bool
+	Snapshots bool
+}
+
+// SourceConfigField is a setting of a participant source, stored in the preferences under SourceSettingKey.
+// Secret settings are shown in a password entry.
+type SourceConfigField struct {
+	Key         string
+	Label       string
+	Placeholder string
+	Secret      bool
+}
+
+// ParticipantSource provides participants for the draw.
+// List returns the stored participants without any request, and Refresh fetches a new list and stores it.
+// Every participant is tagged with the ID of its source. ConfigFields lists the settings the source needs,
+// which the main menu lets the operator edit; sources configured elsewhere, like Patreon, return none.
+type ParticipantSource interface {
+	ID() string
+	Name() string
+	Capabilities() SourceCapabilities
+	ConfigFields() []SourceConfigField
+	List() (*MembersList, error)
+	Refresh(ctx context.Context, onEvent func(FetchEvent)) (*FetchResult, error)
+}
+
+// EntrySource is a participant source whose participants enter themselves during an entry window opened from the main menu,
+// e.g. by typing a keyword in a live chat. EntryKeyword returns the keyword participants enter with. CollectEntries collects the entries until the context is done, reporting every
+// new participant and the number of entries so far to onEntry, then stores them as the list of the source and adds the source
+// to the sources of the draw. If the connection is lost, the entries collected so far are stored and returned with the error.
+type EntrySource interface {
+	ParticipantSource
+	EntryKeyword() string
+	CollectEntries(ctx context.Context, onEntry func(name string, count int)) (*FetchResult, error)
+}
+
+// sources holds the registered participant sources, in registration order.
+var sources = []ParticipantSource{}
+
+// RegisterSource adds a participant source, replacing a source with the same ID.
+func RegisterSource(source ParticipantSource) {
+	for i, registered := range sources {
+		if registered.ID() == source.ID() {
+			sources[i] = source
+			return
+		}
+	}
+	sources = append(sources, source)
+}
+
+// GetSources returns the registered participant sources.
+func GetSources() []ParticipantSource {
+	return sources
+}
+
+// GetSource returns the registered participant source with the given ID.
+func GetSource(id string) (ParticipantSource, bool) {
+	for _, source := range sources {
+		if source.ID() == id {
+			return source, true
+		}
+	}
+	return nil, false
+}
+
+// SourceName returns the name of the participant source with the given ID, the ID itself if no such source is registered.
+func SourceName(id string) string {
+	if source, found := GetSource(id); found {
+		return source.Name()
+	}
+	return id
+}
+
+// SourceSettingKey returns the preferences key of a setting of the participant source with the given ID.
+func SourceSettingKey(sourceID string, key string) string {
+	return commons.SourceSettingsPrefix + sourceID + "." + key
+}
+
+// sourceSetting returns the trimmed value of a setting of the participant source.
+func sourceSetting(sourceID string, key string) string {
+	return strings.TrimSpace(commons.GetPreferences().String(SourceSettingKey(sourceID, key)))
+}
+
+// GetSelectedSourceIDs returns the IDs of the registered sources the operator picked for the draw, Patreon if none is stored.
+func GetSelectedSourceIDs() []string {
+	ids := []string{}
+	for _, id := range commons.GetPreferences().StringListWithFallback(commons.DrawSources, []string{PatreonSourceID}) {
+		if _, found := GetSource(id); found {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// SetSelectedSourceIDs stores the IDs of the sources the draw uses.
+func SetSelectedSourceIDs(ids []string) {
+	commons.GetPreferences().SetStringList(commons.DrawSources, ids)
+}
+
+// SelectSource adds the source with the given ID to the sources the draw uses.
+func SelectSource(id string) {
+	ids := GetSelectedSourceIDs()
+	for _, selected := range ids {
+		if selected == id {
+			return
+		}
+	}
+	SetSelectedSourceIDs(append(ids, id))
+}
+
+// DrawSources returns the sources the draw uses: the sample source in test mode with dummy data,
+// or else the sources picked by the operator.
+func DrawSources() []ParticipantSource {
+	preferences := commons.GetPreferences()
+	if preferences.Bool(commons.TestMode) && !preferences.Bool(commons.UseRealData) {
+		source, _ := GetSource(SampleSourceID)
+		return []ParticipantSource{source}
+	}
+
+	drawSources := []ParticipantSource{}
+	for _, id := range GetSelectedSourceIDs() {
+		source, _ := GetSource(id)
+		drawSources = append(drawSources, source)
+	}
+	return drawSources
+}
+
+// loadSources reads the stored participants of every source and merges them into one list.
+// The tiers and campaigns of every source are put together, and the tiers are arranged again, see TierCatalog.arrange.
+// A source without a stored list is left out; it fails only if no source has a list.
+func loadSources(drawSources []ParticipantSource) ([]PatreonMember, *TierCatalog, bool) {
+	members := []PatreonMember{}
+	catalog := newTierCatalog()
+	loaded := 0
+	for _, source := range drawSources {
+		sourceList, err := sourceMembers(source)
+		if err != nil {
+			commons.GetLogger().Printf("%s: %v", source.Name(), err)
+			continue
+		}
+		loaded++
+		for _, member := range sourceList.PatreonMembers {
+			member.Source = source.ID()
+			members = append(members, member)
+		}
+		for _, tier := range sourceList.Tiers {
+			catalog.add(tier)
+		}
+		catalog.Campaigns = append(catalog.Campaigns, sourceList.Campaigns...)
+	}
+	if loaded == 0 {
+		return nil, nil, false
+	}
+	catalog.arrange()
+	return members, catalog, true
+}
+
+// sourceMembers returns the list of the source the draw uses: the selected snapshot for a source keeping snapshots,
+// if one is selected, or else its stored list. A selected snapshot that can not be loaded is unselected.
+func sourceMembers(source ParticipantSource) (*MembersList, error) {
+	id := GetSelectedSnapshot()
+	if !source.Capabilities().Snapshots || id == "" {
+		return source.List()
+	}
+	if snapshot, ok := loadSnapshot(id); ok {
+		return &MembersList{PatreonMembers: snapshot.Members, Tiers: snapshot.Tiers.Tiers, Campaigns: snapshot.Tiers.Campaigns}, nil
+	}
+	commons.GetLogger().Printf("Snapshot %s could not be loaded, using the latest fetch", id)
+	commons.GetPreferences().RemoveValue(commons.SelectedSnapshot)
+	return source.List()
+}
+
+// DrawUsesSnapshots reports whether one of the sources the draw uses keeps snapshots.
+func DrawUsesSnapshots() bool {
+	for _, source := range DrawSources() {
+		if source.Capabilities().Snapshots {
+			return true
+		}
+	}
+	return false
+}
+
+// RefreshSources refreshes every given source that supports it, one after the other, and returns their merged results.
+// The events of every source are reported to onEvent, which may be nil. The refresh stops at the first error,
+// which names the source when several sources are refreshed; the sources refreshed before keep their new list.
+func RefreshSources(ctx context.Context, refreshed []ParticipantSource, onEvent func(FetchEvent)) (*FetchResult, error) {
+	if len(refreshed) == 0 {
+		return nil, ErrNoSource
+	}
+
+	merged := &FetchResult{Members: []PatreonMember{}, Skipped: []SkippedMember{}, Campaigns: []Campaign{}}
+	for _, source := range refreshed {
+		if !source.Capabilities().Refresh {
+			continue
+		}
+		result, err := source.Refresh(ctx, onEvent)
+		if err != nil {
+			commons.GetLogger().Printf("%s: %v", source.Name(), err)
+			if len(refreshed) > 1 {
+				return nil, fmt.Errorf("%s: %w", source.Name(), err)
+			}
+			return nil, err
+		}
+		mergeFetchResult(merged, result)
+	}
+	return merged, nil
+}
+
+// mergeFetchResult adds the result of a source to the merged result. The roster diffs are merged too.
+func mergeFetchResult(merged *FetchResult, result *FetchResult) {
+	merged.Members = append(merged.Members, result.Members...)
+	merged.Tiers = append(merged.Tiers, result.Tiers...)
+	merged.Campaigns = append(merged.Campaigns, result.Campaigns...)
+	merged.Skipped = append(merged.Skipped, result.Skipped...)
+	merged.Pages += result.Pages
+	merged.Shared += result.Shared
+	if result.ResumedFromPage > 0 {
+		merged.ResumedFromPage = result.ResumedFromPage
+	}
+	if result.Diff == nil {
+		return
+	}
+	if merged.Diff == nil {
+		merged.Diff = &RosterDiff{Date: result.Diff.Date}
+	}
+	merged.Diff.Joined = append(merged.Diff.Joined, result.Diff.Joined...)
+	merged.Diff.Left = append(merged.Diff.Left, result.Diff.Left...)
+	merged.Diff.Upgraded = append(merged.Diff.Upgraded, result.Diff.Upgraded...)
+	merged.Diff.Downgraded = append(merged.Diff.Downgraded, result.Diff.Downgraded...)
+	merged.Diff.TierChanged = append(merged.Diff.TierChanged, result.Diff.TierChanged...)
+	merged.Diff.StatusChanged = append(merged.Diff.StatusChanged, result.Diff.StatusChanged...)
+}
+
+// readStoredList reads a members file and its tiers file into a list. Members read from a file written by an older version
+// get their tier ID looked up by the tier title, see fillTierIDs.
+func readStoredList(membersFileName string, tiersFileName string) (*MembersList, error) {
+	members := []PatreonMember{}
+	if !readAndUnmarshal(commons.GetDataPath(membersFileName), &members) {
+		return nil, fmt.Errorf("%s could not be read", membersFileName)
+	}
+	catalog, ok := readTierCatalog(commons.GetDataPath(tiersFileName))
+	if !ok {
+		return nil, fmt.Errorf("%s could not be read", tiersFileName)
+	}
+	fillTierIDs(members, catalog)
+	return &MembersList{PatreonMembers: members, Tiers: catalog.Tiers, Campaigns: catalog.Campaigns}, nil
+}
+
+// storeSourceList tags the members with the source and writes them with their tier catalog, see writeSnapshot.
+// The list is archived as a snapshot only if the source keeps snapshots.
+func storeSourceList(sourceID string, membersFileName string, tiersFileName string, members []PatreonMember, catalog *TierCatalog) *RosterDiff {
+	for i := range members {
+		members[i].Source = sourceID
+	}
+	source, found := GetSource(sourceID)
+	archive := found && source.Capabilities().Snapshots
+	return writeSnapshot(commons.GetDataPath(membersFileName), commons.GetDataPath(tiersFileName), members, catalog, archive)
+}
+
+func init() {
+	RegisterSource(patreonSource{})
+	RegisterSource(fileSource{})
+	RegisterSource(discordSource{})
+	RegisterSource(twitchSource{})
+	RegisterSource(youtubeSource{})
+	RegisterSource(sampleSource{})
+}
@@ -0,0 +1,54 @@
+package data
+
+import (
+	"context"
+	"pick-a-bro/internal/commons"
+)
+
+// PatreonSourceID is the ID of the source of the members of the Patreon campaigns.
+const PatreonSourceID = "patreon"
+
+// patreonSource provides the eligible members of every campaign with a profile. Its settings are the campaign profiles
+// of the settings panel, so it has no settings of its own.
+type patreonSource struct{}
+
+func (patreonSource) ID() string {
+	return PatreonSourceID
+}
+
+func (patreonSource) Name() string {
+	return "Patreon"
+}
+
+func (patreonSource) Capabilities() SourceCapabilities {
+	return SourceCapabilities{Refresh: true, Tiers: true, Snapshots: true}
+}
+
+func (patreonSource) ConfigFields() []SourceConfigField {
+	return nil
+}
+
+// List reads the members and the tiers stored by the last fetch.
+func (patreonSource) List() (*MembersList, error) {
+	return readStoredList(commons.StructuredData.RealDataFileName, commons.StructuredData.RealTiersFileName)
+}
+
+// Refresh fetches the eligible members and the tiers of every configured campaign and writes them to the local files.
+// It makes sure an authorized Patreon client exists for every campaign profile first, which may require
+// the browser authorization unless a creator access token is used.
+// Every step, and every page received, is reported to onEvent, which may be nil.
+// Cancelling the context aborts a pending authorization or request; the local files are then left untouched.
+// The returned result also lists the members that were skipped and why.
+func (patreonSource) Refresh(ctx context.Context, onEvent func(FetchEvent)) (*FetchResult, error) {
+	result, err := fetchAndProcessRealMembers(ctx, reporter(onEvent))
+	if err != nil {
+		return nil, err
+	}
+
+	commons.GetLogger().Printf("Fetched %d members of %d campaigns in %d pages, skipped %d, %d supporting several campaigns",
+		len(result.Members), len(result.Campaigns), result.Pages, len(result.Skipped), result.Shared)
+	for _, skipped := range result.Skipped {
+		commons.GetLogger().Printf("Skipped %s: %s", skipped.FullName, skipped.Reason)
+	}
+	return result, nil
+}
@@ -3,20 +3,20 @@ package data
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 	"os"
-	"os/exec"
 	"pick-a-bro/internal/commons"
-	"runtime"
+	"strings"
 	"time"
 
 	"github.com/austinbspencer/patreon-go-wrapper"
 	"golang.org/x/oauth2"
 )
 
+var ErrNoCampaign = errors.New("no campaign ID set in the settings")
+
 type AccessTokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
@@ -25,295 +25,508 @@ type AccessTokenResponse struct {
 	TokenType    string `json:"token_type"`
 }
 
+// PatreonMember is an eligible member of a campaign as stored in the members file.
+// Tier holds the tier title and TierID the ID of the tier in the tier catalog.
+// CampaignID is the campaign the entry was fetched from, and Campaigns every campaign the person supports.
+// Entries is the number of entries of a participant imported from a file or edited by the operator, 0 for a single entry.
+// Override marks the participants added, edited or removed by the operator; it is set when the list is loaded and never stored.
+// Source is the ID of the participant source the member comes from, see ParticipantSource.
+// Files written by older versions only contain FullName and Tier, the other fields are then left empty.
 type PatreonMember struct {
+	FullName                     string
+	Tier                         string
+	TierID                       string
+	CampaignID                   string
+	Campaigns                    []string
+	ID                           string
+	UserID                       string
+	Email                        string
+	PatronStatus                 string
+	LastChargeStatus             string
+	CurrentlyEntitledAmountCents int
+	LifetimeSupportCents         int
+	PledgeRelationshipStart      *time.Time
+	Entries                      int
+	Source                       string
+	Override                     string `json:"-"`
+	overrideKey                  string
+}
+
+// EntryCount returns the number of entries of the member, at least 1.
+func (m PatreonMember) EntryCount() int {
+	if m.Entries > 1 {
+		return m.Entries
+	}
+	return 1
+}
+
+// SkippedMember is an eligible member left out of the fetched list, together with the reason.
+type SkippedMember struct {
 	FullName string
-	Tier     string
+	Reason   string
 }
 
-var token *oauth2.Token
-var client *patreon.Client
-
-// newPatreonClient initializes a new Patreon client and establishes a connection to the API.
-// It starts an authentication server, fetches an access token using the received code,
-// and creates a Patreon client with the obtained token.
-// Returns true if the client is successfully created, otherwise false.
-func newPatreonClient() bool {
-	code := startAuthServer()
-	if code == "" {
-		commons.GetLogger().Println("No code received. Unable to establish connection to api")
-		return false
-	}
-	token = fetchToken(code)
-	client = createPatreonClient(token)
-	return true
+// FetchResult is the outcome of a members fetch: the eligible members, the tiers, the campaigns
+// and the members that had to be skipped. Pages is the number of pages received from Patreon,
+// and ResumedFromPage the page an interrupted fetch was continued from, 0 for a fetch started from scratch.
+// Shared is the number of people supporting several campaigns, who are counted once.
+// Diff holds the changes since the previously stored members, nil if there was no previous members file.
+type FetchResult struct {
+	Members         []PatreonMember
+	Tiers           []Tier
+	Campaigns       []Campaign
+	Skipped         []SkippedMember
+	Pages           int
+	ResumedFromPage int
+	Shared          int
+	Diff            *RosterDiff
 }
 
-// startAuthServer starts an HTTP server on port 8080 and waits for a request with a "code" query parameter.
-// It opens the authentication URL in the default browser and returns the received code.
-func startAuthServer() string {
-	done := make(chan bool)
-	var code string
+// Steps of a members fetch reported through FetchEvent
+const (
+	FetchStepAuth  = "auth"
+	FetchStepFetch = "fetch"
+	FetchStepWrite = "write"
+)
 
-	srv := &http.Server{Addr: ":8080"}
+// FetchEvent reports the progress of a members fetch: the current step, the name of the campaign it concerns
+// and, while fetching, the number of pages and eligible members of that campaign received so far.
+// TotalPages is the number of pages of the campaign, 0 while it is unknown.
+type FetchEvent struct {
+	Step       string
+	Campaign   string
+	Page       int
+	TotalPages int
+	Members    int
+}
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		code = r.URL.Query().Get("code")
-		done <- true
-	})
+// totalPages returns the number of pages of a campaign with the given number of members, as reported by the API,
+// at the page size in the preferences. It returns 0 if the number of members is unknown.
+func totalPages(members int) int {
+	pageSize := getPageSize()
+	if members <= 0 || pageSize <= 0 {
+		return 0
+	}
+	return (members + pageSize - 1) / pageSize
+}
 
-	go func() {
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			commons.GetLogger().Println(err)
+// reporter returns a function sending the events of a fetch to onEvent, which may be nil.
+func reporter(onEvent func(FetchEvent)) func(FetchEvent) {
+	return func(event FetchEvent) {
+		if onEvent != nil {
+			onEvent(event)
 		}
-	}()
+	}
+}
 
-	openAuthURLInBrowser()
+// profileClient is the Patreon client of a profile, together with the token source it was created from
+// and the authorization mode set when it was created.
+type profileClient struct {
+	client      *patreon.Client
+	tokenSource oauth2.TokenSource
+	authMode    string
+}
 
-	<-done
+// clients holds the Patreon client of every profile authorized in this session, by profile ID.
+var clients = map[string]*profileClient{}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		commons.GetLogger().Println(err)
-	}
-	return code
+// setClient creates the profile's Patreon client from the token source and keeps it for the next fetches.
+func setClient(profile Profile, source oauth2.TokenSource, authMode string) *patreon.Client {
+	c := &profileClient{client: createPatreonClient(source), tokenSource: source, authMode: authMode}
+	clients[profile.ID] = c
+	return c.client
 }
 
-// openAuthURLInBrowser opens the authentication URL in the default web browser.
-// It constructs the authentication URL using the provided client ID and redirect URI.
-// The URL is then opened in the default web browser based on the operating system.
-// If an error occurs while opening the URL, it is logged and a fatal error is raised.
-func openAuthURLInBrowser() {
-	authParams := url.Values{
-		"response_type": {"code"},
-		"client_id":     {commons.GetPreferences().String(commons.ClientId)},
-		"redirect_uri":  {commons.RedirectURI},
+// newPatreonClient initializes a new Patreon client for the profile and establishes a connection to the API.
+// It first tries the token stored by a previous session, refreshing it if it has expired.
+// Only when no token is stored or the refresh fails, it runs the browser authorization,
+// and persists the obtained token for the next sessions. In manual authorization mode
+// the browser is never opened and ErrManualAuthRequired is returned instead.
+// Returns an error if no client could be created.
+func newPatreonClient(ctx context.Context, profile Profile, authMode string) (*patreon.Client, error) {
+	keys := oauthTokenKeys(profile)
+	if stored := loadToken(keys); stored != nil {
+		source := newTokenSource(profile, keys, stored)
+		_, err := source.Token()
+		if err == nil {
+			return setClient(profile, source, authMode), nil
+		}
+		commons.GetLogger().Printf("Stored token of %s could not be refreshed, falling back to browser authorization: %v", profile.Name(), err)
+		clearToken(keys)
 	}
 
-	authenticationUrl := fmt.Sprintf("%s?%s", patreon.AuthorizationURL, authParams.Encode())
-
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.Command("cmd", "/c", "start", authenticationUrl)
-	case "darwin":
-		cmd = exec.Command("open", authenticationUrl)
-	default:
-		cmd = exec.Command("xdg-open", authenticationUrl)
+	if authMode == commons.AuthModes.Manual {
+		return nil, ErrManualAuthRequired
 	}
 
-	if err := cmd.Start(); err != nil {
-		commons.GetLogger().Fatalf("error: %v", err)
+	token, err := authorize(ctx, profile)
+	if err != nil {
+		commons.GetLogger().Printf("Unable to establish connection to api: %v", err)
+		return nil, err
 	}
+	saveToken(keys, token)
+	return setClient(profile, newTokenSource(profile, keys, token), authMode), nil
 }
 
-// fetchToken fetches an OAuth2 token using the provided authorization code.
-// It sends a POST request to the Patreon API to exchange the code for a token.
-// The token is then parsed from the response and returned as an oauth2.Token.
-// If any error occurs during the process, it will be logged and the function will return nil.
-func fetchToken(code string) *oauth2.Token {
-	data := url.Values{
-		"code":          {code},
-		"grant_type":    {"authorization_code"},
-		"client_id":     {commons.GetPreferences().String(commons.ClientId)},
-		"client_secret": {commons.GetPreferences().String(commons.ClientSecret)},
-		"redirect_uri":  {commons.RedirectURI},
+// newCreatorClient creates a Patreon client from the profile's creator access token set in the preferences,
+// without any authorization flow. If a creator refresh token is set together with the client ID and secret,
+// the token is refreshed once it expires; a token of unknown expiry is refreshed right away to learn it.
+// Otherwise the creator token is used as is.
+func newCreatorClient(profile Profile) (*patreon.Client, error) {
+	keys := creatorTokenKeys(profile)
+	token := loadToken(keys)
+	if token == nil {
+		return nil, ErrCreatorTokenMissing
 	}
 
-	resp, err := http.PostForm(patreon.AccessTokenURL, data)
-	if err != nil {
-		commons.GetLogger().Fatalf("error: %v", err)
+	var source oauth2.TokenSource
+	if token.RefreshToken != "" && profile.setting(commons.ClientId) != "" && profile.setting(commons.ClientSecret) != "" {
+		if token.Expiry.IsZero() {
+			token.Expiry = time.Now()
+		}
+		source = newTokenSource(profile, keys, token)
+	} else {
+		token.Expiry = time.Time{}
+		source = oauth2.StaticTokenSource(token)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		commons.GetLogger().Fatalf("error: %v", err)
+	if _, err := source.Token(); err != nil {
+		return nil, fmt.Errorf("creator token refresh failed: %w", err)
 	}
+	return setClient(profile, source, commons.AuthModes.Creator), nil
+}
 
-	var respOAuthToken AccessTokenResponse
-	if err := json.Unmarshal(body, &respOAuthToken); err != nil {
-		commons.GetLogger().Fatalf("error: %v", err)
+// ensurePatreonClient returns a Patreon client of the profile with a valid token.
+// With a creator access token the client is always built from the token in the preferences.
+// Otherwise, if the profile already has a client, its token source is asked for a token, which refreshes an expired token.
+// A new client is created through newPatreonClient when there is no client yet or the refresh fails.
+func ensurePatreonClient(ctx context.Context, profile Profile) (*patreon.Client, error) {
+	authMode := profile.setting(commons.AuthMode)
+	if authMode == commons.AuthModes.Creator {
+		return newCreatorClient(profile)
 	}
 
-	return &oauth2.Token{
-		AccessToken:  respOAuthToken.AccessToken,
-		RefreshToken: respOAuthToken.RefreshToken,
-		Expiry:       time.Now().Add(2 * time.Hour),
+	if c, ok := clients[profile.ID]; ok && c.authMode != commons.AuthModes.Creator {
+		_, err := c.tokenSource.Token()
+		if err == nil {
+			c.authMode = authMode
+			return c.client, nil
+		}
+		commons.GetLogger().Printf("Token refresh of %s failed: %v", profile.Name(), err)
+		clearToken(oauthTokenKeys(profile))
 	}
+	return newPatreonClient(ctx, profile, authMode)
 }
 
-// createPatreonClient creates a new Patreon client using the provided OAuth2 token.
+// createPatreonClient creates a new Patreon client using the provided OAuth2 token source.
+// The token source refreshes the token transparently whenever the client makes a request with an expired token,
+// and the requests go through a retryTransport, which retries them when Patreon's rate limit is hit.
 // It returns the initialized Patreon client.
-func createPatreonClient(token *oauth2.Token) *patreon.Client {
-	config := oauth2.Config{
-		ClientID:     commons.GetPreferences().String(commons.ClientId),
-		ClientSecret: commons.GetPreferences().String(commons.ClientSecret),
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  patreon.AuthorizationURL,
-			TokenURL: patreon.AccessTokenURL,
-		},
-		Scopes: patreon.AllScopes,
-	}
-
-	tc := config.Client(context.Background(), token)
-
-	return patreon.NewClient(tc)
+func createPatreonClient(source oauth2.TokenSource) *patreon.Client {
+	httpClient := &http.Client{Transport: newRetryTransport(http.DefaultTransport)}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	return patreon.NewClient(oauth2.NewClient(ctx, source))
 }
 
-func FetchMembersToLocalStorage() ([]PatreonMember, map[string]interface{}) {
-	testMode := commons.GetPreferences().Bool(commons.TestMode)
+// contextTransport binds every request going through it to a context.
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
 
-	if !token.Valid() && !testMode {
-		if !newPatreonClient() {
-			return nil, nil
-		}
-	}
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
 
-	members, tiers, err := getMembers(testMode)
-	if err != nil {
-		commons.GetLogger().Println(err)
+// clientWithContext returns a copy of the Patreon client whose requests are bound to the context,
+// so cancelling the context aborts a pending request, including a retry waiting for the rate limit.
+func clientWithContext(ctx context.Context, c *patreon.Client) *patreon.Client {
+	httpClient := *c.Client()
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
 	}
-
-	return members, tiers
+	httpClient.Transport = &contextTransport{ctx: ctx, base: base}
+	return patreon.NewClient(&httpClient)
 }
 
+// getFilePath returns the path in the data directory of the test file in test mode, or else of the real file.
 func getFilePath(testFileName string, realFileName string) string {
 	if commons.GetPreferences().Bool(commons.TestMode) {
-		return testFileName
-	}
-	return realFileName
-}
-
-func getMembers(testMode bool) ([]PatreonMember, map[string]interface{}, error) {
-	useRealData := commons.GetPreferences().Bool(commons.UseRealData)
-	membersFilePath := getFilePath(commons.StructuredData.TestDataFileName, commons.StructuredData.RealDataFileName)
-	tiersFilePath := getFilePath(commons.StructuredData.TestTiersFileName, commons.StructuredData.RealTiersFileName)
-	if testMode && !useRealData {
-		return getTestMembers(membersFilePath, tiersFilePath)
+		return commons.GetDataPath(testFileName)
 	}
-	return fetchAndProcessRealMembers(membersFilePath, tiersFilePath)
+	return commons.GetDataPath(realFileName)
 }
 
-// getTestMembers reads a sample data file containing Patreon members and their tier information,
-// processes this data, and writes the results to specified files.
+// fetchAndProcessRealMembers fetches the tiers and the members of every campaign with a profile and processes
+// their information, including tiers they are entitled to. The campaigns are merged into one list, see mergeCampaigns,
+// and the members' information and the tier catalog are written to the files of the Patreon source.
+// Requests rejected by the rate limit are retried by the clients' transport. If a campaign still fails,
+// the progress of every campaign is saved to the fetch progress file: the next fetch skips the campaigns
+// already fetched and continues the failed one from the failed page. If the failed campaign had pages received,
+// a FetchInterruptedError is returned. A cancelled fetch returns the context error and writes nothing.
 //
 // Parameters:
-// - membersFilePath: The path to the file where the processed list of members will be saved.
-// - tiersFilePath: The path to the file where the processed tiers' details will be saved.
+// - ctx: The context the requests are bound to.
+// - report: Called with the progress of every campaign and before writing the files.
 //
 // Returns:
-//   - A slice of PatreonMember, representing the members parsed from the sample data.
-//   - A map[string]interface{}, representing the tiers' details as parsed from the sample data.
-//     The structure of this map depends on the processing logic of the getTiersMap function.
-//   - An error, which is non-nil if any errors occurred during the execution of the function.
-func getTestMembers(membersFilePath string, tiersFilePath string) ([]PatreonMember, map[string]interface{}, error) {
-	data, err := commons.GetSamplesFS().ReadFile("tests/samples/patreons.json")
-	if err != nil {
-		commons.GetLogger().Fatalf("failed to read samples file: %v", err)
+//   - A FetchResult with the campaigns' members and tiers fetched from Patreon, the skipped members and the pages fetched.
+//   - An error, which is non-nil if any errors occurred during the function's execution.
+func fetchAndProcessRealMembers(ctx context.Context, report func(FetchEvent)) (*FetchResult, error) {
+	profiles := campaignProfiles()
+	if len(profiles) == 0 {
+		return nil, ErrNoCampaign
 	}
 
-	var membersResp *patreon.MembersResponse
-	if err := json.Unmarshal(data, &membersResp); err != nil {
-		return nil, nil, err
-	}
-	commons.GetLogger().Print("Members test data generated")
+	result := &FetchResult{Campaigns: []Campaign{}}
+	progresses := loadFetchProgress()
+	fetched := []*fetchProgress{}
+	for _, profile := range profiles {
+		campaign := Campaign{ID: profile.CampaignID(), Name: profile.Name()}
+		if progress := progresses[campaign.ID]; progress != nil && !progress.Done && result.ResumedFromPage == 0 {
+			result.ResumedFromPage = progress.Pages + 1
+		}
 
-	tiersMap := getTiersMap(membersResp)
-	writeToFile(tiersFilePath, tiersMap)
+		progress, err := fetchCampaignMembers(ctx, profile, campaign, progresses[campaign.ID], report)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if progress != nil && progress.Pages > 0 {
+				progresses[campaign.ID] = progress
+				saveFetchProgress(progresses)
+				return nil, &FetchInterruptedError{Campaign: campaign.Name, Pages: progress.Pages, Members: len(progress.Members), Err: err}
+			}
+			if len(fetched) > 0 {
+				saveFetchProgress(progresses)
+			}
+			return nil, fmt.Errorf("%s: %w", campaign.Name, err)
+		}
 
-	membersList := getMembersList(membersResp, tiersMap)
-	writeToFile(membersFilePath, membersList)
+		progresses[campaign.ID] = progress
+		fetched = append(fetched, progress)
+		result.Campaigns = append(result.Campaigns, campaign)
+		result.Pages += progress.Pages
+	}
 
-	return membersList, tiersMap, nil
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	members, skipped, catalog, shared := mergeCampaigns(result.Campaigns, fetched)
+	report(FetchEvent{Step: FetchStepWrite, Members: len(members)})
+	result.Members, result.Skipped, result.Tiers, result.Shared = members, skipped, catalog.Tiers, shared
+	result.Diff = storeSourceList(PatreonSourceID, commons.StructuredData.RealDataFileName, commons.StructuredData.RealTiersFileName, result.Members, catalog)
+	clearFetchProgress()
+	return result, nil
 }
 
-// fetchAndProcessRealMembers fetches members from a Patreon campaign and processes their information,
-// including tiers they are entitled to. It writes the members' information and tiers' details to specified files.
-//
-// Parameters:
-// - membersFilePath: The path to the file where the list of members will be saved.
-// - tiersFilePath: The path to the file where the tiers' details will be saved.
-//
-// Returns:
-//   - A slice of PatreonMember, representing the campaign members fetched from Patreon.
-//   - A map[string]interface{}, representing the tiers' details. The exact structure of the map depends on
-//     the structure of the tiers information returned by the Patreon API and processed by getTiersMap function.
-//   - An error, which is non-nil if any errors occurred during the function's execution.
-func fetchAndProcessRealMembers(membersFilePath string, tiersFilePath string) ([]PatreonMember, map[string]interface{}, error) {
-	membersList := []PatreonMember{}
-	var nextCursor string
-	var tiersMap map[string]interface{}
+// fetchCampaignMembers fetches the tiers and the eligible members of the profile's campaign,
+// after making sure the profile has an authorized client. The catalog starts from the campaign's tiers,
+// so tiers without members are listed too, and is completed with the tiers included in every members page.
+// Pages hold as many members as the page size in the preferences.
+// The fetch continues from the given progress of an interrupted fetch, if any; a campaign already Done is not fetched again.
+// On error, the progress made so far is returned together with the error.
+func fetchCampaignMembers(ctx context.Context, profile Profile, campaign Campaign, progress *fetchProgress, report func(FetchEvent)) (*fetchProgress, error) {
+	if progress != nil && progress.Done {
+		commons.GetLogger().Printf("%s was already fetched by the interrupted fetch", campaign.Name)
+		report(FetchEvent{Step: FetchStepFetch, Campaign: campaign.Name, Page: progress.Pages, TotalPages: progress.Pages, Members: len(progress.Members)})
+		return progress, nil
+	}
+
+	report(FetchEvent{Step: FetchStepAuth, Campaign: campaign.Name})
+	patreonClient, err := ensurePatreonClient(ctx, profile)
+	if err != nil {
+		return progress, err
+	}
+	client := clientWithContext(ctx, patreonClient)
+
+	if progress != nil {
+		commons.GetLogger().Printf("Continuing the interrupted fetch of %s from page %d", campaign.Name, progress.Pages+1)
+	} else {
+		catalog, err := fetchCampaignTiers(client, campaign.ID)
+		if err != nil {
+			return nil, err
+		}
+		progress = &fetchProgress{CampaignID: campaign.ID, Members: []PatreonMember{}, Skipped: []SkippedMember{}, Tiers: catalog}
+	}
+	report(FetchEvent{Step: FetchStepFetch, Campaign: campaign.Name, Page: progress.Pages, Members: len(progress.Members)})
 
 	for {
-		membersResp, err := client.FetchCampaignMembers(commons.GetPreferences().String(commons.CampaignId),
-			patreon.WithIncludes("currently_entitled_tiers"),
+		membersResp, err := client.FetchCampaignMembers(campaign.ID,
+			patreon.WithIncludes(commons.WithIncludes...),
 			patreon.WithFields("member", commons.MemberFields...),
 			patreon.WithFields("tier", commons.TierFields...),
-			patreon.WithCursor(nextCursor),
+			patreon.WithFields("user", commons.UserFields...),
+			patreon.WithPageSize(getPageSize()),
+			patreon.WithCursor(progress.Cursor),
 		)
-
 		if err != nil {
-			return nil, nil, err
+			return progress, err
 		}
 
-		if tiersMap == nil {
-			tiersMap = getTiersMap(membersResp)
-			writeToFile(tiersFilePath, tiersMap)
-		}
-
-		membersList = append(membersList, getMembersList(membersResp, tiersMap)...)
-
-		nextCursor = membersResp.Meta.Pagination.Cursors.Next
-		if nextCursor == "" {
-			break
+		progress.Tiers.addFromIncludes(membersResp.Included)
+		members, skipped := getMembersList(membersResp, progress.Tiers)
+		progress.Members = append(progress.Members, members...)
+		progress.Skipped = append(progress.Skipped, skipped...)
+		progress.Pages++
+		report(FetchEvent{Step: FetchStepFetch, Campaign: campaign.Name, Page: progress.Pages, TotalPages: totalPages(membersResp.Meta.Count), Members: len(progress.Members)})
+
+		progress.Cursor = membersResp.Meta.Pagination.Cursors.Next
+		if progress.Cursor == "" {
+			progress.Done = true
+			return progress, nil
 		}
 	}
-	writeToFile(membersFilePath, membersList)
-	return membersList, tiersMap, nil
 }
 
-// getTiersMap returns a map of Patreon tier IDs to their corresponding titles.
-func getTiersMap(members *patreon.MembersResponse) map[string]interface{} {
-	tiersMap := make(map[string]interface{})
-	for _, item := range members.Included.Items {
-		if tier, ok := item.(*patreon.Tier); ok {
-			tiersMap[tier.ID] = tier.Attributes.Title
-		}
+// getPageSize returns the number of members requested per page, as set in the preferences.
+func getPageSize() int {
+	return commons.GetPreferences().IntWithFallback(commons.PageSize, commons.DefaultPageSize)
+}
+
+// fetchCampaignTiers fetches every tier of the campaign, published or not, into a new tier catalog.
+func fetchCampaignTiers(client *patreon.Client, campaignID string) (*TierCatalog, error) {
+	campaignResp, err := client.FetchCampaign(campaignID,
+		patreon.WithIncludes("tiers"),
+		patreon.WithFields("tier", commons.TierFields...),
+	)
+	if err != nil {
+		return nil, err
 	}
-	return tiersMap
+
+	catalog := newTierCatalog()
+	catalog.addFromIncludes(campaignResp.Included)
+	return catalog, nil
 }
 
-// getMembersList retrieves a list of active and paid Patreon members from the given `members` response
-// and maps them to a slice of `PatreonMember` structs. It filters out members who are not active patrons
-// or whose last charge status is not "Paid".
+// getMembersList retrieves a list of eligible Patreon members from the given `members` response
+// and maps them to a slice of `PatreonMember` structs. It filters out members whose patron status
+// or last charge status is not allowed by the eligibility settings in the preferences.
+// Members without an entitled tier are put in the "No tier" bucket, which is added to the tier catalog.
+// Members with several tiers get their highest tier by amount, or one entry per tier,
+// depending on the multi-tier policy in the preferences. Tiers missing from the catalog are ignored,
+// and a member whose tiers are all missing is skipped.
 //
 // Parameters:
 // - members: A pointer to a `patreon.MembersResponse` object containing the Patreon members data.
-// - tiersMap: A map of Patreon tier IDs to their corresponding names.
+// - catalog: The tier catalog the members' tiers are looked up in.
 //
 // Returns:
-// - A slice of `PatreonMember` structs representing the active and paid Patreon members.
-func getMembersList(members *patreon.MembersResponse, tiersMap map[string]interface{}) []PatreonMember {
+// - A slice of `PatreonMember` structs representing the eligible Patreon members.
+// - A slice of `SkippedMember` structs with the members that were left out and why.
+func getMembersList(members *patreon.MembersResponse, catalog *TierCatalog) ([]PatreonMember, []SkippedMember) {
 	membersList := []PatreonMember{}
+	skipped := []SkippedMember{}
+	allTiers := commons.GetPreferences().StringWithFallback(commons.MultiTierPolicy, commons.MultiTierPolicies.Highest) == commons.MultiTierPolicies.All
+	eligibility := loadEligibilityFilter()
+
 	for _, member := range members.Data {
-		if member.Attributes.PatronStatus == "active_patron" && member.Attributes.LastChargeStatus == "Paid" {
-			membersList = append(membersList, PatreonMember{
-				FullName: member.Attributes.FullName,
-				Tier:     tiersMap[member.Relationships.CurrentlyEntitledTiers.Data[0].ID].(string),
-			})
+		if !eligibility.isEligible(member.Attributes) {
+			continue
+		}
+
+		tierIDs, missing := getMemberTiers(member, catalog)
+		if len(missing) > 0 {
+			reason := fmt.Sprintf("entitled tier %s not found", strings.Join(missing, ", "))
+			if len(tierIDs) == 0 {
+				skipped = append(skipped, SkippedMember{FullName: member.Attributes.FullName, Reason: reason})
+				continue
+			}
+			commons.GetLogger().Printf("%s: %s, using the remaining tiers", member.Attributes.FullName, reason)
+		}
+
+		switch {
+		case len(tierIDs) == 0:
+			catalog.add(Tier{ID: commons.NoTierID, Title: GetNoTierName(), Published: true})
+			tierIDs = []string{commons.NoTierID}
+		case !allTiers:
+			tierIDs = []string{getHighestTier(tierIDs, catalog)}
+		}
+
+		for _, tierID := range tierIDs {
+			tier, _ := catalog.Get(tierID)
+			membersList = append(membersList, newPatreonMember(member, tier))
 		}
 	}
-	return membersList
+	return membersList, skipped
+}
+
+// newPatreonMember maps a member of the Patreon response to a PatreonMember of the given tier.
+func newPatreonMember(member patreon.Member, tier Tier) PatreonMember {
+	patreonMember := PatreonMember{
+		FullName:                     member.Attributes.FullName,
+		Tier:                         tier.Title,
+		TierID:                       tier.ID,
+		ID:                           member.ID,
+		Email:                        member.Attributes.Email,
+		PatronStatus:                 member.Attributes.PatronStatus,
+		LastChargeStatus:             member.Attributes.LastChargeStatus,
+		CurrentlyEntitledAmountCents: member.Attributes.CurrentlyEntitledAmountCents,
+		LifetimeSupportCents:         member.Attributes.LifetimeSupportCents,
+	}
+	if member.Relationships.User != nil {
+		patreonMember.UserID = member.Relationships.User.Data.ID
+	}
+	if member.Attributes.PledgeRelationshipStart.Valid {
+		start := member.Attributes.PledgeRelationshipStart.Time
+		patreonMember.PledgeRelationshipStart = &start
+	}
+	return patreonMember
 }
 
+// getMemberTiers returns the IDs of the tiers the member is entitled to that are present in the tier catalog,
+// and the IDs of the ones that are missing from it.
+func getMemberTiers(member patreon.Member, catalog *TierCatalog) ([]string, []string) {
+	tierIDs := []string{}
+	missing := []string{}
+	if member.Relationships.CurrentlyEntitledTiers == nil {
+		return tierIDs, missing
+	}
+
+	for _, tier := range member.Relationships.CurrentlyEntitledTiers.Data {
+		if _, ok := catalog.Get(tier.ID); ok {
+			tierIDs = append(tierIDs, tier.ID)
+		} else {
+			missing = append(missing, tier.ID)
+		}
+	}
+	return tierIDs, missing
+}
+
+// getHighestTier returns the ID of the tier with the highest amount. On equal amounts the first tier wins.
+func getHighestTier(tierIDs []string, catalog *TierCatalog) string {
+	highest, _ := catalog.Get(tierIDs[0])
+	for _, tierID := range tierIDs[1:] {
+		if tier, _ := catalog.Get(tierID); tier.AmountCents > highest.AmountCents {
+			highest = tier
+		}
+	}
+	return highest.ID
+}
+
+// GetNoTierName returns the name of the bucket for members without a tier, as set in the preferences.
+func GetNoTierName() string {
+	if name := strings.TrimSpace(commons.GetPreferences().String(commons.NoTierName)); name != "" {
+		return name
+	}
+	return commons.DefaultNoTierName
+}
+
+// writeToFile writes the data as indented JSON to the file. The JSON is first written to a temporary file
+// that then replaces the file, so the file never holds half-written JSON.
 func writeToFile(filePath string, data interface{}) {
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		commons.GetLogger().Fatalf("error %v", err)
 	}
 
-	if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, jsonData, 0644); err != nil {
+		commons.GetLogger().Fatalf("error %v", err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
 		commons.GetLogger().Fatalf("error %v", err)
 	}
 	commons.GetLogger().Printf("%s generated", filePath)
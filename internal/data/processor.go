@@ -3,44 +3,41 @@ package data
 import (
 	"encoding/json"
 	"image/color"
-	"math/rand"
 	"os"
 	"pick-a-bro/internal/commons"
 )
 
 type MembersList struct {
 	PatreonMembers []PatreonMember
-	Tiers          map[string]interface{}
+	Tiers          []Tier
+	Campaigns      []Campaign
 	ColorCode      map[string]color.Color
 }
 
 var list *MembersList
 
-// ExtractDataFromFile reads and extracts data from files based on the test mode and real data preferences.
-// It checks if the members file and tiers file exist and are readable, and then generates color codes.
+// listedMembers are the members of the loaded list as fetched, before the overrides of the operator are applied.
+var listedMembers []PatreonMember
+
+// ExtractDataFromFile reads the stored participants of the sources the draw uses, see DrawSources,
+// and merges them into one list. In test mode with dummy data, the sample source is used.
+// Then it generates the color codes of the tiers.
+// If a snapshot is selected for the draw, the members of the source keeping snapshots are read from it instead, see sourceMembers.
+// The changes of the operator are applied to the loaded members, see applyOverrides.
 // Returns true if the data extraction is successful, otherwise returns false.
 func ExtractDataFromFile() bool {
-	testMode := commons.GetPreferences().Bool(commons.TestMode)
-	useRealData := commons.GetPreferences().Bool(commons.UseRealData)
-	membersFileName := commons.StructuredData.RealDataFileName
-	tiersFileName := commons.StructuredData.RealTiersFileName
-	if testMode && !useRealData {
-		membersFileName = commons.StructuredData.TestDataFileName
-		tiersFileName = commons.StructuredData.TestTiersFileName
-	}
-
 	list = &MembersList{}
-	// Check if the members file exists and is readable
-	if !readAndUnmarshal(membersFileName, &list.PatreonMembers) {
-		return false
-	}
-
-	// Check if the tiers file exists and is readable
-	if !readAndUnmarshal(tiersFileName, &list.Tiers) {
+	members, catalog, ok := loadSources(DrawSources())
+	if !ok {
 		return false
 	}
+	listedMembers = members
+	list.Tiers = catalog.Tiers
+	list.Campaigns = catalog.Campaigns
 
+	migrateTierChances(list.Tiers)
 	generateColorCodes()
+	list.PatreonMembers = applyOverrides(listedMembers, loadOverrides())
 
 	return true
 }
@@ -53,11 +50,35 @@ func SetMembersList(membersList []PatreonMember) {
 	list.PatreonMembers = membersList
 }
 
-func SetTiersMap(tiersMap map[string]interface{}) {
-	list.Tiers = tiersMap
+func SetTiers(tiers []Tier, campaigns []Campaign) {
+	list.Tiers = tiers
+	list.Campaigns = campaigns
+	migrateTierChances(list.Tiers)
 	generateColorCodes()
 }
 
+// listTier returns the tier of the loaded list with the given ID.
+func listTier(id string) (Tier, bool) {
+	for _, tier := range list.Tiers {
+		if tier.ID == id {
+			return tier, true
+		}
+	}
+	return Tier{}, false
+}
+
+// fillTierIDs sets the tier ID of the members read from a file written by an older version,
+// looking the tier up by its title.
+func fillTierIDs(members []PatreonMember, catalog *TierCatalog) {
+	for i, member := range members {
+		if member.TierID == "" {
+			if tier, found := catalog.GetByTitle(member.Tier); found {
+				members[i].TierID = tier.ID
+			}
+		}
+	}
+}
+
 func readAndUnmarshal(filePath string, v interface{}) bool {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -73,29 +94,12 @@ func readAndUnmarshal(filePath string, v interface{}) bool {
 	return true
 }
 
-// generateColorCodes generates color codes for each tier in the list.
-// It assigns predefined colors to tiers and generates random colors for any additional tiers.
+// generateColorCodes maps the ID of each tier in the list to its display color.
 // The color codes are stored in the `ColorCode` field of the list.
 func generateColorCodes() {
-	colors := []color.Color{
-		color.RGBA{R: 0, G: 0, B: 255, A: 255},   // Blue
-		color.RGBA{R: 0, G: 150, B: 0, A: 255},   // Green
-		color.RGBA{R: 100, G: 100, B: 0, A: 255}, // Yellow
-		color.RGBA{R: 255, G: 0, B: 0, A: 255},   // Red
-	}
-
-	// If there are more tiers than predefined colors, generate random colors for the additional tiers
-	if len(list.Tiers) > len(colors) {
-		for i := 0; i < len(list.Tiers)-len(colors); i++ {
-			colors = append(colors, color.RGBA{R: uint8(rand.Intn(255)), G: uint8(rand.Intn(255)), B: uint8(rand.Intn(255)), A: 255})
-		}
-	}
-
 	tierColors := make(map[string]color.Color)
-	i := 0
 	for _, tier := range list.Tiers {
-		tierColors[tier.(string)] = colors[i]
-		i++
+		tierColors[tier.ID] = tier.DisplayColor()
 	}
 
 	list.ColorCode = tierColors
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"pick-a-bro/internal/commons"
+	"strconv"
+	"strings"
+)
+
+// Profile is a set of Patreon credentials together with the campaign they fetch.
+// The default profile, with an empty ID, keeps the settings of the versions with a single campaign;
+// the IDs of the other profiles are listed under commons.CampaignProfiles.
+type Profile struct {
+	ID string
+}
+
+// Key returns the preferences key of the profile's setting, see commons.ProfileKey.
+func (p Profile) Key(key string) string {
+	return commons.ProfileKey(p.ID, key)
+}
+
+// setting returns the value of the profile's setting stored in the preferences.
+func (p Profile) setting(key string) string {
+	return commons.GetPreferences().String(p.Key(key))
+}
+
+// CampaignID returns the ID of the campaign the profile fetches, empty if none is set.
+func (p Profile) CampaignID() string {
+	return strings.TrimSpace(p.setting(commons.CampaignId))
+}
+
+// Name returns the campaign name set for the profile. Without a name the default profile is called
+// the main campaign, and the other profiles are named after their campaign ID.
+func (p Profile) Name() string {
+	if name := strings.TrimSpace(p.setting(commons.CampaignName)); name != "" {
+		return name
+	}
+	if p.ID == "" {
+		return commons.GetTranslation(commons.I18n.MainCampaign)
+	}
+	if campaignID := p.CampaignID(); campaignID != "" {
+		return campaignID
+	}
+	return commons.GetTranslation(commons.I18n.Campaign) + " " + p.ID
+}
+
+// GetProfiles returns the default profile followed by the added ones.
+func GetProfiles() []Profile {
+	profiles := []Profile{{}}
+	for _, id := range commons.GetPreferences().StringList(commons.CampaignProfiles) {
+		profiles = append(profiles, Profile{ID: id})
+	}
+	return profiles
+}
+
+// AddProfile adds a new profile with no settings and returns it. It does not pick the profile shown in the settings,
+// the caller does. Profile IDs are increasing numbers, never reused while the profile exists,
+// so the settings of a removed profile can not leak into a new one.
+func AddProfile() Profile {
+	preferences := commons.GetPreferences()
+	ids := preferences.StringList(commons.CampaignProfiles)
+	next := 2
+	for _, id := range ids {
+		if n, err := strconv.Atoi(id); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+
+	profile := Profile{ID: strconv.Itoa(next)}
+	preferences.SetStringList(commons.CampaignProfiles, append(ids, profile.ID))
+	return profile
+}
+
+// RemoveProfile removes an added profile together with its settings and its client.
+// The default profile can not be removed.
+func RemoveProfile(profile Profile) {
+	if profile.ID == "" {
+		return
+	}
+
+	preferences := commons.GetPreferences()
+	for _, key := range commons.ProfileSettings {
+		preferences.RemoveValue(profile.Key(key))
+	}
+
+	ids := []string{}
+	for _, id := range preferences.StringList(commons.CampaignProfiles) {
+		if id != profile.ID {
+			ids = append(ids, id)
+		}
+	}
+	preferences.SetStringList(commons.CampaignProfiles, ids)
+	delete(clients, profile.ID)
+}
+
+// campaignProfiles returns the profiles with a campaign ID set, the ones a fetch goes through.
+// Profiles set to the same campaign are fetched once, through the first of them.
+func campaignProfiles() []Profile {
+	profiles := []Profile{}
+	seen := map[string]bool{}
+	for _, profile := range GetProfiles() {
+		campaignID := profile.CampaignID()
+		if campaignID == "" || seen[campaignID] {
+			continue
+		}
+		seen[campaignID] = true
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
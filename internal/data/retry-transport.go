@@ -0,0 +1,99 @@
+package data
+
+import (
+	"net/http"
+	"pick-a-bro/internal/commons"
+	"strconv"
+	"time"
+)
+
+// retryTransport is an http.RoundTripper that retries requests rejected by the rate limit (429)
+// or failing with a temporary server error. It waits for the delay given by the Retry-After header,
+// or else for an exponentially growing delay, and gives up after commons.MaxFetchRetries retries.
+// Only requests without a body are retried, which covers every request of the Patreon client.
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+// newRetryTransport wraps the given transport with the retry handling.
+func newRetryTransport(base http.RoundTripper) *retryTransport {
+	return &retryTransport{base: base}
+}
+
+// RoundTrip sends the request, retrying it while the response asks for it and retries are left.
+// Network errors are retried the same way. Waiting stops early if the request's context is cancelled.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= commons.MaxFetchRetries || req.Body != nil || (err == nil && !isRetryable(resp.StatusCode)) {
+			return resp, err
+		}
+		if req.Context().Err() != nil {
+			return resp, err
+		}
+
+		delay := backoffDelay(attempt)
+		if err != nil {
+			commons.GetLogger().Printf("Request to %s failed, retrying in %s: %v", req.URL.Path, delay, err)
+		} else {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = retryAfter
+			}
+			commons.GetLogger().Printf("Request to %s returned %s, retrying in %s", req.URL.Path, resp.Status, delay)
+			resp.Body.Close()
+		}
+
+		if err := sleepWithContext(req, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isRetryable reports whether a response with the given status code is worth retrying.
+func isRetryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoffDelay returns the delay before the retry following the given attempt:
+// commons.RetryBaseDelay doubled on every attempt, capped at commons.RetryMaxDelay.
+func backoffDelay(attempt int) time.Duration {
+	delay := commons.RetryBaseDelay << attempt
+	if delay <= 0 || delay > commons.RetryMaxDelay {
+		return commons.RetryMaxDelay
+	}
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// sleepWithContext waits for the given delay, returning early with the context error if the request is cancelled.
+func sleepWithContext(req *http.Request, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
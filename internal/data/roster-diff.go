@@ -0,0 +1,181 @@
+package data
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RosterDiff lists the changes between the previously stored members and a new fetch.
+// Members entitled to several tiers are compared by their highest tier. TierChanged holds the moves
+// between tiers that are neither higher nor lower, see compareTiers.
+type RosterDiff struct {
+	Date          time.Time
+	Joined        []PatreonMember
+	Left          []PatreonMember
+	Upgraded      []TierChange
+	Downgraded    []TierChange
+	TierChanged   []TierChange
+	StatusChanged []StatusChange
+}
+
+// TierChange is a member whose tier changed between two fetches, with the old and new tier titles.
+type TierChange struct {
+	FullName string
+	From     string
+	To       string
+}
+
+// StatusChange is a member whose patron status or last charge status changed between two fetches.
+type StatusChange struct {
+	FullName             string
+	FromPatronStatus     string
+	ToPatronStatus       string
+	FromLastChargeStatus string
+	ToLastChargeStatus   string
+}
+
+// IsEmpty reports whether nothing changed between the two fetches.
+func (d *RosterDiff) IsEmpty() bool {
+	return len(d.Joined) == 0 && len(d.Left) == 0 && len(d.Upgraded) == 0 && len(d.Downgraded) == 0 && len(d.TierChanged) == 0 &&
+		len(d.StatusChanged) == 0
+}
+
+// writeSnapshot writes the tier catalog and the members to their files, replacing the previous ones.
+// Before replacing them, the new members are compared with the stored ones; if something changed,
+// the diff is written to a dated file next to the members file. The members are only compared with the previous
+// members of the same file, so every source has its own diffs. If archive is set, a versioned copy is kept in the snapshots directory.
+// It returns the diff, or nil if there was no previous members file to compare with.
+func writeSnapshot(membersFilePath string, tiersFilePath string, members []PatreonMember, catalog *TierCatalog, archive bool) *RosterDiff {
+	var diff *RosterDiff
+	if previousMembers, previousCatalog, ok := readPreviousSnapshot(membersFilePath, tiersFilePath); ok {
+		diff = computeRosterDiff(previousMembers, previousCatalog, members, catalog)
+		if !diff.IsEmpty() {
+			writeToFile(rosterDiffFilePath(membersFilePath, diff.Date), diff)
+		}
+	}
+
+	writeToFile(tiersFilePath, catalog)
+	writeToFile(membersFilePath, members)
+	if archive {
+		archiveSnapshot(members, catalog)
+	}
+	return diff
+}
+
+// readPreviousSnapshot reads the stored members and tier catalog. A missing or unreadable tiers file
+// gives an empty catalog, so the members can still be compared by tier title.
+func readPreviousSnapshot(membersFilePath string, tiersFilePath string) ([]PatreonMember, *TierCatalog, bool) {
+	if _, err := os.Stat(membersFilePath); err != nil {
+		return nil, nil, false
+	}
+
+	members := []PatreonMember{}
+	if !readAndUnmarshal(membersFilePath, &members) {
+		return nil, nil, false
+	}
+	catalog, ok := readTierCatalog(tiersFilePath)
+	if !ok {
+		catalog = newTierCatalog()
+	}
+	fillTierIDs(members, catalog)
+	return members, catalog, true
+}
+
+// rosterDiffFilePath returns the path of the diff file written on the given date,
+// named after the members file, e.g. eligle_patreons_diff_2006-01-02_150405.json.
+func rosterDiffFilePath(membersFilePath string, date time.Time) string {
+	return strings.TrimSuffix(membersFilePath, ".json") + "_diff_" + date.Format("2006-01-02_150405") + ".json"
+}
+
+// computeRosterDiff compares the previous members with the new ones. Members are matched by their person key, see PersonKey,
+// so a person whose highest tier moves to another of the merged campaigns is not reported as leaving one and joining another.
+// They are matched by their full name when the previous members file was written by a version that did not store IDs.
+func computeRosterDiff(previous []PatreonMember, previousCatalog *TierCatalog, current []PatreonMember, currentCatalog *TierCatalog) *RosterDiff {
+	byName := false
+	for _, member := range previous {
+		if member.ID == "" {
+			byName = true
+			break
+		}
+	}
+
+	previousByKey := highestTierMembers(previous, previousCatalog, byName)
+	currentByKey := highestTierMembers(current, currentCatalog, byName)
+
+	diff := &RosterDiff{
+		Date:          time.Now(),
+		Joined:        []PatreonMember{},
+		Left:          []PatreonMember{},
+		Upgraded:      []TierChange{},
+		Downgraded:    []TierChange{},
+		TierChanged:   []TierChange{},
+		StatusChanged: []StatusChange{},
+	}
+	for key, member := range currentByKey {
+		old, found := previousByKey[key]
+		if !found {
+			diff.Joined = append(diff.Joined, member)
+			continue
+		}
+
+		if old.TierID != member.TierID || old.Tier != member.Tier {
+			change := TierChange{FullName: member.FullName, From: old.Tier, To: member.Tier}
+			switch order := compareTiers(memberTier(member, currentCatalog), memberTier(old, previousCatalog)); {
+			case order > 0:
+				diff.Upgraded = append(diff.Upgraded, change)
+			case order < 0:
+				diff.Downgraded = append(diff.Downgraded, change)
+			default:
+				diff.TierChanged = append(diff.TierChanged, change)
+			}
+		}
+
+		// Files written by older versions have no statuses to compare with
+		if old.PatronStatus != "" && (old.PatronStatus != member.PatronStatus || old.LastChargeStatus != member.LastChargeStatus) {
+			diff.StatusChanged = append(diff.StatusChanged, StatusChange{
+				FullName:             member.FullName,
+				FromPatronStatus:     old.PatronStatus,
+				ToPatronStatus:       member.PatronStatus,
+				FromLastChargeStatus: old.LastChargeStatus,
+				ToLastChargeStatus:   member.LastChargeStatus,
+			})
+		}
+	}
+	for key, member := range previousByKey {
+		if _, found := currentByKey[key]; !found {
+			diff.Left = append(diff.Left, member)
+		}
+	}
+
+	sort.Slice(diff.Joined, func(i, j int) bool { return diff.Joined[i].FullName < diff.Joined[j].FullName })
+	sort.Slice(diff.Left, func(i, j int) bool { return diff.Left[i].FullName < diff.Left[j].FullName })
+	sort.Slice(diff.Upgraded, func(i, j int) bool { return diff.Upgraded[i].FullName < diff.Upgraded[j].FullName })
+	sort.Slice(diff.Downgraded, func(i, j int) bool { return diff.Downgraded[i].FullName < diff.Downgraded[j].FullName })
+	sort.Slice(diff.TierChanged, func(i, j int) bool { return diff.TierChanged[i].FullName < diff.TierChanged[j].FullName })
+	sort.Slice(diff.StatusChanged, func(i, j int) bool { return diff.StatusChanged[i].FullName < diff.StatusChanged[j].FullName })
+	return diff
+}
+
+// highestTierMembers maps every member to its entry of the highest tier, keyed by person key or full name.
+// The members of a file all come from one source, and older files do not store it, so the source is left out of the key.
+func highestTierMembers(members []PatreonMember, catalog *TierCatalog, byName bool) map[string]PatreonMember {
+	byKey := make(map[string]PatreonMember, len(members))
+	for _, member := range members {
+		key := PersonKey(PatreonMember{UserID: member.UserID, ID: member.ID, FullName: member.FullName})
+		if byName {
+			key = member.FullName
+		}
+		if existing, found := byKey[key]; !found || compareTiers(memberTier(member, catalog), memberTier(existing, catalog)) > 0 {
+			byKey[key] = member
+		}
+	}
+	return byKey
+}
+
+// memberTier returns the member's tier in the catalog, an empty tier if it is not in the catalog.
+func memberTier(member PatreonMember, catalog *TierCatalog) Tier {
+	tier, _ := catalog.Get(member.TierID)
+	return tier
+}
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+// rosterCatalog returns a catalog of a Bronze, a Silver and a Gold tier in the campaigns one and two, the Gold tiers at the same amount.
+func rosterCatalog() *TierCatalog {
+	catalog := newTierCatalog()
+	for _, tier := range []Tier{
+		{ID: "one:bronze", CampaignID: "one", Title: "Bronze", AmountCents: 300},
+		{ID: "one:silver", CampaignID: "one", Title: "Silver", AmountCents: 500},
+		{ID: "one:gold", CampaignID: "one", Title: "Gold", AmountCents: 1000},
+		{ID: "two:gold", CampaignID: "two", Title: "Gold of two", AmountCents: 1000},
+	} {
+		catalog.add(tier)
+	}
+	return catalog
+}
+
+func TestComputeRosterDiff(t *testing.T) {
+	catalog := rosterCatalog()
+	previous := []PatreonMember{
+		{FullName: "Alice", ID: "one-1", UserID: "1", TierID: "one:gold", Tier: "Gold", CampaignID: "one"},
+		{FullName: "Bob", ID: "one-2", UserID: "2", TierID: "one:silver", Tier: "Silver", CampaignID: "one"},
+		{FullName: "Carol", ID: "one-3", UserID: "3", TierID: "one:silver", Tier: "Silver", CampaignID: "one"},
+		{FullName: "Dave", ID: "one-4", UserID: "4", TierID: "one:bronze", Tier: "Bronze", CampaignID: "one"},
+	}
+	current := []PatreonMember{
+		// Alice's highest tier is now in campaign two, where she is another member, at the same amount
+		{FullName: "Alice", ID: "one-1", UserID: "1", TierID: "one:silver", Tier: "Silver", CampaignID: "one"},
+		{FullName: "Alice", ID: "two-1", UserID: "1", TierID: "two:gold", Tier: "Gold of two", CampaignID: "two"},
+		{FullName: "Bob", ID: "one-2", UserID: "2", TierID: "one:gold", Tier: "Gold", CampaignID: "one"},
+		{FullName: "Carol", ID: "one-3", UserID: "3", TierID: "one:bronze", Tier: "Bronze", CampaignID: "one"},
+		{FullName: "Erin", ID: "two-5", UserID: "5", TierID: "two:gold", Tier: "Gold of two", CampaignID: "two"},
+	}
+
+	diff := computeRosterDiff(previous, catalog, current, catalog)
+	if names := memberNames(diff.Joined); !reflect.DeepEqual(names, []string{"Erin"}) {
+		t.Errorf("joined %v, want Erin", names)
+	}
+	if names := memberNames(diff.Left); !reflect.DeepEqual(names, []string{"Dave"}) {
+		t.Errorf("left %v, want Dave", names)
+	}
+	if want := []TierChange{{FullName: "Bob", From: "Silver", To: "Gold"}}; !reflect.DeepEqual(diff.Upgraded, want) {
+		t.Errorf("upgraded %v, want %v", diff.Upgraded, want)
+	}
+	if want := []TierChange{{FullName: "Carol", From: "Silver", To: "Bronze"}}; !reflect.DeepEqual(diff.Downgraded, want) {
+		t.Errorf("downgraded %v, want %v", diff.Downgraded, want)
+	}
+	if want := []TierChange{{FullName: "Alice", From: "Gold", To: "Gold of two"}}; !reflect.DeepEqual(diff.TierChanged, want) {
+		t.Errorf("tier changed %v, want %v", diff.TierChanged, want)
+	}
+}
+
+func TestComputeRosterDiffOlderFiles(t *testing.T) {
+	catalog := rosterCatalog()
+	current := []PatreonMember{
+		{FullName: "Alice", ID: "one-1", UserID: "1", Source: PatreonSourceID, TierID: "one:gold", Tier: "Gold"},
+		{FullName: "Bob", ID: "one-2", UserID: "2", Source: PatreonSourceID, TierID: "one:silver", Tier: "Silver"},
+	}
+
+	// A file of a version without IDs is compared by name, and one without sources by person key
+	for name, previous := range map[string][]PatreonMember{
+		"without IDs": {
+			{FullName: "Alice", TierID: "one:gold", Tier: "Gold"},
+			{FullName: "Bob", TierID: "one:silver", Tier: "Silver"},
+		},
+		"without sources": {
+			{FullName: "Alice", ID: "one-1", UserID: "1", TierID: "one:gold", Tier: "Gold"},
+			{FullName: "Bob", ID: "one-2", UserID: "2", TierID: "one:silver", Tier: "Silver"},
+		},
+	} {
+		if diff := computeRosterDiff(previous, catalog, current, catalog); !diff.IsEmpty() {
+			t.Errorf("%s: diff %+v, want none", name, diff)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"pick-a-bro/internal/commons"
+
+	"github.com/austinbspencer/patreon-go-wrapper"
+)
+
+// SampleSourceID is the ID of the source of the sample members used by the test mode.
+const SampleSourceID = "sample"
+
+// sampleCampaignID is the campaign the sample members of the test mode are tagged with.
+const sampleCampaignID = "test"
+
+// sampleSource provides the members of the sample Patreon response embedded in the app, for the test mode.
+type sampleSource struct{}
+
+func (sampleSource) ID() string {
+	return SampleSourceID
+}
+
+func (sampleSource) Name() string {
+	return commons.GetTranslation(commons.I18n.TestDummyData)
+}
+
+func (sampleSource) Capabilities() SourceCapabilities {
+	return SourceCapabilities{Refresh: true, Tiers: true, Sample: true, Snapshots: true}
+}
+
+func (sampleSource) ConfigFields() []SourceConfigField {
+	return nil
+}
+
+// List reads the sample members and tiers generated by the last refresh.
+func (sampleSource) List() (*MembersList, error) {
+	return readStoredList(commons.StructuredData.TestDataFileName, commons.StructuredData.TestTiersFileName)
+}
+
+// Refresh reads a sample data file containing Patreon members and their tier information,
+// processes this data as a fetch would, and writes the results to the test files.
+// The sample members are tagged with sampleCampaignID, named after the default profile.
+//
+// Returns:
+//   - A FetchResult with the members and the tiers parsed from the sample data, and the skipped members.
+//   - An error, which is non-nil if any errors occurred during the execution of the function.
+func (sampleSource) Refresh(ctx context.Context, onEvent func(FetchEvent)) (*FetchResult, error) {
+	data, err := commons.GetSamplesFS().ReadFile("tests/samples/patreons.json")
+	if err != nil {
+		commons.GetLogger().Fatalf("failed to read samples file: %v", err)
+	}
+
+	var membersResp *patreon.MembersResponse
+	if err := json.Unmarshal(data, &membersResp); err != nil {
+		return nil, err
+	}
+	commons.GetLogger().Print("Members test data generated")
+
+	progress := &fetchProgress{CampaignID: sampleCampaignID, Pages: 1, Done: true, Tiers: newTierCatalog()}
+	progress.Tiers.addFromIncludes(membersResp.Included)
+	progress.Members, progress.Skipped = getMembersList(membersResp, progress.Tiers)
+
+	result := &FetchResult{Pages: 1, Campaigns: []Campaign{{ID: sampleCampaignID, Name: GetProfiles()[0].Name()}}}
+	members, skipped, catalog, shared := mergeCampaigns(result.Campaigns, []*fetchProgress{progress})
+	result.Members, result.Skipped, result.Tiers, result.Shared = members, skipped, catalog.Tiers, shared
+	result.Diff = storeSourceList(SampleSourceID, commons.StructuredData.TestDataFileName, commons.StructuredData.TestTiersFileName, result.Members, catalog)
+
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package data
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"pick-a-bro/internal/commons"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+// setupTestData gives the test fresh preferences, a data directory of its own, a silent logger and the English translations.
+func setupTestData(t *testing.T) {
+	t.Helper()
+	commons.SetPreferences(test.NewApp().Preferences())
+	commons.SetLogger(log.New(io.Discard, "", 0))
+	commons.SetDataDir(t.TempDir())
+
+	bundle := i18n.NewBundle(language.English)
+	if _, err := bundle.LoadMessageFile(filepath.Join("..", "locale", "en-US.json")); err != nil {
+		t.Fatal(err)
+	}
+	commons.SetLocalization(i18n.NewLocalizer(bundle, "en-US"))
+}
+
+// setSourceSettings stores the settings of the participant source, see SourceSettingKey.
+func setSourceSettings(sourceID string, settings map[string]string) {
+	for key, value := range settings {
+		commons.GetPreferences().SetString(SourceSettingKey(sourceID, key), value)
+	}
+}
+
+// readFixture returns the content of a file of the testdata directory.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+// memberNames returns the names of the members, in order.
+func memberNames(members []PatreonMember) []string {
+	names := make([]string, len(members))
+	for i, member := range members {
+		names[i] = member.FullName
+	}
+	return names
+}
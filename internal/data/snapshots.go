@@ -0,0 +1,147 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"pick-a-bro/internal/commons"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotTimeLayout is the layout of the creation time at the start of a snapshot ID.
+const snapshotTimeLayout = "20060102-150405"
+
+// Snapshot is a dated copy of the eligible members and the tier catalog of a fetch,
+// kept in the snapshots directory to prove who was eligible at the time of a draw.
+// Hash is the SHA-256 of the members and tiers, so a modified snapshot can be told apart.
+type Snapshot struct {
+	CreatedAt time.Time
+	Hash      string
+	Members   []PatreonMember
+	Tiers     *TierCatalog
+}
+
+// SnapshotInfo describes a stored snapshot without reading it.
+type SnapshotInfo struct {
+	ID        string
+	CreatedAt time.Time
+	Hash      string
+}
+
+// snapshotsDir returns the directory of the snapshots, a separate one in test mode.
+func snapshotsDir() string {
+	return getFilePath(commons.StructuredData.TestSnapshotsDir, commons.StructuredData.SnapshotsDir)
+}
+
+// snapshotHash returns the hex encoded SHA-256 of the JSON encoding of the members and the tiers.
+func snapshotHash(members []PatreonMember, tiers *TierCatalog) string {
+	content, err := json.Marshal(struct {
+		Members []PatreonMember
+		Tiers   *TierCatalog
+	}{members, tiers})
+	if err != nil {
+		commons.GetLogger().Fatalf("error %v", err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// archiveSnapshot stores the members and tiers of a fetch of the source keeping snapshots as a new snapshot,
+// named after its creation time and the start of its hash, and prunes the snapshots older than the retention period.
+// The draw then uses the new list of that source, so the previously selected snapshot is unselected.
+func archiveSnapshot(members []PatreonMember, tiers *TierCatalog) {
+	if err := os.MkdirAll(snapshotsDir(), 0755); err != nil {
+		commons.GetLogger().Printf("Unable to create the snapshots directory: %v", err)
+		return
+	}
+
+	snapshot := Snapshot{CreatedAt: time.Now(), Hash: snapshotHash(members, tiers), Members: members, Tiers: tiers}
+	id := snapshot.CreatedAt.Format(snapshotTimeLayout) + "_" + snapshot.Hash[:12]
+	writeToFile(filepath.Join(snapshotsDir(), id+".json"), snapshot)
+
+	commons.GetPreferences().RemoveValue(commons.SelectedSnapshot)
+	pruneSnapshots(id)
+}
+
+// ListSnapshots returns the stored snapshots, newest first.
+func ListSnapshots() []SnapshotInfo {
+	files, err := os.ReadDir(snapshotsDir())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			commons.GetLogger().Print(err)
+		}
+		return []SnapshotInfo{}
+	}
+
+	snapshots := []SnapshotInfo{}
+	for _, file := range files {
+		if info, ok := parseSnapshotID(strings.TrimSuffix(file.Name(), ".json")); ok && !file.IsDir() && filepath.Ext(file.Name()) == ".json" {
+			snapshots = append(snapshots, info)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt) })
+	return snapshots
+}
+
+// parseSnapshotID reads the creation time and the hash prefix from a snapshot ID.
+func parseSnapshotID(id string) (SnapshotInfo, bool) {
+	createdAt, hash, found := strings.Cut(id, "_")
+	if !found {
+		return SnapshotInfo{}, false
+	}
+	date, err := time.ParseInLocation(snapshotTimeLayout, createdAt, time.Local)
+	if err != nil {
+		return SnapshotInfo{}, false
+	}
+	return SnapshotInfo{ID: id, CreatedAt: date, Hash: hash}, true
+}
+
+// GetSelectedSnapshot returns the ID of the snapshot the draw uses for the source keeping snapshots, empty for the latest fetch.
+func GetSelectedSnapshot() string {
+	return commons.GetPreferences().String(commons.SelectedSnapshot)
+}
+
+// SelectSnapshot makes the draw use the snapshot with the given ID, or the latest fetch for an empty ID,
+// and loads its members and tiers. Returns false if the data could not be loaded.
+func SelectSnapshot(id string) bool {
+	commons.GetPreferences().SetString(commons.SelectedSnapshot, id)
+	return ExtractDataFromFile()
+}
+
+// loadSnapshot reads the snapshot with the given ID. A snapshot whose content no longer matches
+// its hash is still loaded, but the mismatch is logged.
+func loadSnapshot(id string) (*Snapshot, bool) {
+	snapshot := &Snapshot{}
+	if !readAndUnmarshal(filepath.Join(snapshotsDir(), id+".json"), snapshot) || snapshot.Tiers == nil {
+		return nil, false
+	}
+	if snapshotHash(snapshot.Members, snapshot.Tiers) != snapshot.Hash {
+		commons.GetLogger().Printf("Snapshot %s does not match its hash, it was modified after the fetch", id)
+	}
+	return snapshot, true
+}
+
+// pruneSnapshots removes the snapshots older than the retention period set in the preferences.
+// A retention of 0 days keeps every snapshot. The snapshot with the given ID is always kept.
+func pruneSnapshots(keep string) {
+	days := commons.GetPreferences().IntWithFallback(commons.SnapshotRetentionDays, 0)
+	if days <= 0 {
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	for _, snapshot := range ListSnapshots() {
+		if snapshot.ID == keep || !snapshot.CreatedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(snapshotsDir(), snapshot.ID+".json")); err != nil {
+			commons.GetLogger().Print(err)
+			continue
+		}
+		commons.GetLogger().Printf("Snapshot %s pruned", snapshot.ID)
+	}
+}
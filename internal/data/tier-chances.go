@@ -0,0 +1,79 @@
+package data
+
+import (
+	"pick-a-bro/internal/commons"
+	"sort"
+)
+
+// GetTierChances returns the chances set for the tier with the given ID, 1 if none is set.
+// Chances may be fractions; chances of 0 leave the members of the tier out of the draw.
+func GetTierChances(tierID string) float64 {
+	return commons.GetPreferences().FloatWithFallback(commons.TierChancesPrefix+tierID, 1)
+}
+
+// SetTierChances stores the chances of the tier under its ID.
+// The tier title is stored next to it, so a setting left behind by a removed tier can still be named.
+func SetTierChances(tier Tier, chances float64) {
+	preferences := commons.GetPreferences()
+	preferences.SetFloat(commons.TierChancesPrefix+tier.ID, chances)
+	preferences.SetString(commons.TierChancesTitlePrefix+tier.ID, tier.Title)
+	rememberTierChances(tier.ID)
+}
+
+// StaleTierChances returns the titles of the tiers that have chances stored but are not among the given tiers.
+func StaleTierChances(tiers []Tier) []string {
+	preferences := commons.GetPreferences()
+	current := map[string]bool{}
+	for _, tier := range tiers {
+		current[tier.ID] = true
+	}
+
+	stale := []string{}
+	for _, id := range preferences.StringList(commons.TierChancesIDs) {
+		if !current[id] {
+			stale = append(stale, preferences.StringWithFallback(commons.TierChancesTitlePrefix+id, id))
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// rememberTierChances adds the tier ID to the list of tiers with stored chances.
+func rememberTierChances(tierID string) {
+	preferences := commons.GetPreferences()
+	ids := preferences.StringList(commons.TierChancesIDs)
+	for _, id := range ids {
+		if id == tierID {
+			return
+		}
+	}
+	preferences.SetStringList(commons.TierChancesIDs, append(ids, tierID))
+}
+
+// migrateTierChances moves the chances stored by older versions under "chances" + tier title
+// to the ID of every tier with that title. The title keyed setting is removed afterwards,
+// so it is migrated only once. A chances setting already stored under the tier ID is kept.
+func migrateTierChances(tiers []Tier) {
+	preferences := commons.GetPreferences()
+	migrated := map[string]bool{}
+	for _, tier := range tiers {
+		legacyKey := commons.LegacyTierChancesPrefix + tier.Title
+		if legacyKey == commons.ChancesPerUser {
+			continue
+		}
+		chances := preferences.IntWithFallback(legacyKey, 0)
+		if chances == 0 {
+			continue
+		}
+		// Chances of 0 are a setting of their own, so only a missing setting is migrated
+		if preferences.FloatWithFallback(commons.TierChancesPrefix+tier.ID, -1) < 0 {
+			SetTierChances(tier, float64(chances))
+			commons.GetLogger().Printf("Chances of tier %s migrated to its ID %s", tier.Title, tier.ID)
+		}
+		migrated[legacyKey] = true
+	}
+
+	for legacyKey := range migrated {
+		preferences.RemoveValue(legacyKey)
+	}
+}
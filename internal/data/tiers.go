@@ -0,0 +1,175 @@
+package data
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"pick-a-bro/internal/commons"
+	"sort"
+
+	"github.com/austinbspencer/patreon-go-wrapper"
+)
+
+// TiersSchemaVersion is the version of the tiers file layout written by this version of the app.
+// Files without a version are the legacy map of tier IDs to titles.
+const TiersSchemaVersion = 2
+
+// tierPalette holds the display colors of the first tiers, in tier order.
+// Tiers beyond the palette get a color derived from their ID, so it stays the same across fetches.
+var tierPalette = []color.RGBA{
+	{R: 0, G: 0, B: 255, A: 255},   // Blue
+	{R: 0, G: 150, B: 0, A: 255},   // Green
+	{R: 100, G: 100, B: 0, A: 255}, // Yellow
+	{R: 255, G: 0, B: 0, A: 255},   // Red
+}
+
+// Tier is a tier of a campaign. CampaignID is empty for the "No tier" bucket, shared by every campaign,
+// and for the tiers of files written before several campaigns could be fetched.
+// Rank orders tiers of equal amount, a higher rank being a higher tier, e.g. the Discord roles, which have no amount,
+// by their position on the server. Order is the position of the tier in the catalog, see TierCatalog.arrange.
+type Tier struct {
+	ID          string
+	CampaignID  string
+	Title       string
+	AmountCents int
+	Rank        int
+	Published   bool
+	Color       string
+	Order       int
+}
+
+// TierCatalog is the content of the tiers file: every tier of the fetched campaigns, sorted by Order,
+// and the campaigns they were fetched from.
+type TierCatalog struct {
+	Version   int
+	Tiers     []Tier
+	Campaigns []Campaign
+}
+
+// DisplayColor returns the color used for the tier in the rules view and on the lottery board.
+func (t Tier) DisplayColor() color.Color {
+	var c color.RGBA
+	if _, err := fmt.Sscanf(t.Color, "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return color.Gray{Y: 128}
+	}
+	c.A = 255
+	return c
+}
+
+// newTierCatalog creates an empty catalog of the current schema version.
+func newTierCatalog() *TierCatalog {
+	return &TierCatalog{Version: TiersSchemaVersion, Tiers: []Tier{}, Campaigns: []Campaign{}}
+}
+
+// Get returns the tier with the given ID.
+func (c *TierCatalog) Get(id string) (Tier, bool) {
+	for _, tier := range c.Tiers {
+		if tier.ID == id {
+			return tier, true
+		}
+	}
+	return Tier{}, false
+}
+
+// GetByTitle returns the first tier with the given title.
+func (c *TierCatalog) GetByTitle(title string) (Tier, bool) {
+	for _, tier := range c.Tiers {
+		if tier.Title == title {
+			return tier, true
+		}
+	}
+	return Tier{}, false
+}
+
+// add adds the tier to the catalog, replacing a tier with the same ID.
+func (c *TierCatalog) add(tier Tier) {
+	for i := range c.Tiers {
+		if c.Tiers[i].ID == tier.ID {
+			c.Tiers[i] = tier
+			return
+		}
+	}
+	c.Tiers = append(c.Tiers, tier)
+}
+
+// addFromIncludes adds the tiers found in the included items of a Patreon response.
+func (c *TierCatalog) addFromIncludes(includes patreon.Includes) {
+	for _, item := range includes.Items {
+		if tier, ok := item.(*patreon.Tier); ok {
+			c.add(Tier{
+				ID:          tier.ID,
+				Title:       tier.Attributes.Title,
+				AmountCents: tier.Attributes.AmountCents,
+				Published:   tier.Attributes.Published,
+			})
+		}
+	}
+}
+
+// arrange sorts the tiers by amount and rank, see compareTiers, then by title, and assigns their order and display color.
+// The "No tier" bucket always comes last.
+func (c *TierCatalog) arrange() {
+	sort.SliceStable(c.Tiers, func(i, j int) bool {
+		a, b := c.Tiers[i], c.Tiers[j]
+		if (a.ID == commons.NoTierID) != (b.ID == commons.NoTierID) {
+			return b.ID == commons.NoTierID
+		}
+		if order := compareTiers(a, b); order != 0 {
+			return order < 0
+		}
+		return a.Title < b.Title
+	})
+
+	for i := range c.Tiers {
+		c.Tiers[i].Order = i
+		c.Tiers[i].Color = tierColor(i, c.Tiers[i].ID)
+	}
+}
+
+// compareTiers returns a negative number if tier a is lower than tier b, a positive one if it is higher, 0 if they are equal:
+// tiers are compared by amount, then by rank.
+func compareTiers(a Tier, b Tier) int {
+	if a.AmountCents != b.AmountCents {
+		return a.AmountCents - b.AmountCents
+	}
+	return a.Rank - b.Rank
+}
+
+// tierColor returns the hex color of the tier at the given position, taken from the palette
+// or derived from the tier ID for positions beyond it.
+func tierColor(position int, id string) string {
+	c := color.RGBA{A: 255}
+	if position < len(tierPalette) {
+		c = tierPalette[position]
+	} else {
+		h := fnv.New32a()
+		h.Write([]byte(id))
+		sum := h.Sum32()
+		c.R, c.G, c.B = uint8(sum>>16), uint8(sum>>8), uint8(sum)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// readTierCatalog reads the tiers file. A legacy file, holding a map of tier IDs to titles,
+// is converted to a catalog with the tiers in title order.
+func readTierCatalog(filePath string) (*TierCatalog, bool) {
+	catalog := &TierCatalog{}
+	if !readAndUnmarshal(filePath, catalog) {
+		return nil, false
+	}
+	if catalog.Version > 0 {
+		return catalog, true
+	}
+
+	legacy := map[string]string{}
+	if !readAndUnmarshal(filePath, &legacy) {
+		return nil, false
+	}
+	catalog = newTierCatalog()
+	for id, title := range legacy {
+		catalog.add(Tier{ID: id, Title: title, Published: true})
+	}
+	catalog.arrange()
+	commons.GetLogger().Printf("%s converted from the legacy tiers format", filePath)
+	return catalog, true
+}
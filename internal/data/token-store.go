@@ -0,0 +1,126 @@
+package data
+
+import (
+	"context"
+	"pick-a-bro/internal/commons"
+	"time"
+
+	"github.com/austinbspencer/patreon-go-wrapper"
+	"golang.org/x/oauth2"
+)
+
+// tokenKeys holds the preference keys under which a token is stored.
+type tokenKeys struct {
+	accessToken  string
+	refreshToken string
+	expiry       string
+}
+
+// oauthTokenKeys returns the keys storing the profile's token obtained through the OAuth authorization.
+func oauthTokenKeys(profile Profile) tokenKeys {
+	return tokenKeys{
+		accessToken:  profile.Key(commons.AccessToken),
+		refreshToken: profile.Key(commons.RefreshToken),
+		expiry:       profile.Key(commons.TokenExpiry),
+	}
+}
+
+// creatorTokenKeys returns the keys storing the profile's creator access token pasted in the preferences.
+func creatorTokenKeys(profile Profile) tokenKeys {
+	return tokenKeys{
+		accessToken:  profile.Key(commons.CreatorAccessToken),
+		refreshToken: profile.Key(commons.CreatorRefreshToken),
+		expiry:       profile.Key(commons.CreatorTokenExpiry),
+	}
+}
+
+// persistingTokenSource wraps an oauth2.TokenSource and saves every new token it hands out
+// to the preferences, so a token refreshed during a session is still available after a restart.
+type persistingTokenSource struct {
+	source oauth2.TokenSource
+	keys   tokenKeys
+	last   *oauth2.Token
+}
+
+// Token returns a valid token from the wrapped source, refreshing it if needed.
+// Whenever the source returns a token different from the last one seen, the new token is persisted.
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	t, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.last == nil || s.last.AccessToken != t.AccessToken || s.last.RefreshToken != t.RefreshToken {
+		saveToken(s.keys, t)
+		s.last = t
+	}
+	return t, nil
+}
+
+// oauthConfig returns the OAuth2 configuration for the Patreon API built from the profile's client credentials.
+func oauthConfig(profile Profile) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     profile.setting(commons.ClientId),
+		ClientSecret: profile.setting(commons.ClientSecret),
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   patreon.AuthorizationURL,
+			TokenURL:  patreon.AccessTokenURL,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		Scopes: commons.Scopes,
+	}
+}
+
+// newTokenSource creates a token source that starts from the given token, refreshes it through the Patreon token endpoint
+// with the profile's client credentials once it expires and persists every refreshed token under the given keys.
+func newTokenSource(profile Profile, keys tokenKeys, token *oauth2.Token) oauth2.TokenSource {
+	return &persistingTokenSource{
+		source: oauthConfig(profile).TokenSource(context.Background(), token),
+		keys:   keys,
+		last:   token,
+	}
+}
+
+// loadToken reads the token stored under the given keys from the preferences.
+// It returns nil if no token has been stored yet.
+func loadToken(keys tokenKeys) *oauth2.Token {
+	preferences := commons.GetPreferences()
+	accessToken := preferences.String(keys.accessToken)
+	if accessToken == "" {
+		return nil
+	}
+
+	var expiry time.Time
+	if unix := preferences.Int(keys.expiry); unix > 0 {
+		expiry = time.Unix(int64(unix), 0)
+	}
+
+	return &oauth2.Token{
+		AccessToken:  accessToken,
+		RefreshToken: preferences.String(keys.refreshToken),
+		TokenType:    "Bearer",
+		Expiry:       expiry,
+	}
+}
+
+// saveToken stores the access token, the refresh token and the expiry time in the preferences.
+// A zero expiry is stored as 0, meaning the token never expires.
+func saveToken(keys tokenKeys, token *oauth2.Token) {
+	preferences := commons.GetPreferences()
+	preferences.SetString(keys.accessToken, token.AccessToken)
+	preferences.SetString(keys.refreshToken, token.RefreshToken)
+
+	expiry := 0
+	if !token.Expiry.IsZero() {
+		expiry = int(token.Expiry.Unix())
+	}
+	preferences.SetInt(keys.expiry, expiry)
+}
+
+// clearToken removes the token stored under the given keys from the preferences.
+func clearToken(keys tokenKeys) {
+	preferences := commons.GetPreferences()
+	preferences.RemoveValue(keys.accessToken)
+	preferences.RemoveValue(keys.refreshToken)
+	preferences.RemoveValue(keys.expiry)
+}
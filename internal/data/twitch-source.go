@@ -0,0 +1,190 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"pick-a-bro/internal/commons"
+	"strings"
+	"time"
+)
+
+// TwitchSourceID is the ID of the source of the viewers entering from the Twitch chat.
+const TwitchSourceID = "twitch"
+
+// twitchServer is the Twitch chat IRC server, used unless another server is set, e.g. a local IRC server for testing.
+const twitchServer = "irc.chat.twitch.tv:6667"
+
+// Settings of the Twitch source, see SourceSettingKey
+const (
+	twitchChannelSetting  = "channel"
+	twitchUsernameSetting = "username"
+	twitchTokenSetting    = "token"
+	twitchServerSetting   = "server"
+	twitchKeywordSetting  = "keyword"
+)
+
+var ErrNoTwitchChannel = errors.New("no Twitch channel set")
+var ErrTwitchLogin = errors.New("the Twitch chat refused the login")
+
+// twitchSource provides the viewers who entered the draw by typing the keyword in the chat of a Twitch channel
+// while the entry window was open. Without a token, the chat is read anonymously.
+type twitchSource struct{}
+
+func (twitchSource) ID() string {
+	return TwitchSourceID
+}
+
+func (twitchSource) Name() string {
+	return "Twitch"
+}
+
+func (twitchSource) Capabilities() SourceCapabilities {
+	return SourceCapabilities{}
+}
+
+func (twitchSource) ConfigFields() []SourceConfigField {
+	return []SourceConfigField{
+		{Key: twitchChannelSetting, Label: commons.GetTranslation(commons.I18n.ChatChannel), Placeholder: "channel"},
+		{Key: twitchKeywordSetting, Label: commons.GetTranslation(commons.I18n.EntryKeyword), Placeholder: DefaultEntryKeyword},
+		{Key: twitchUsernameSetting, Label: commons.GetTranslation(commons.I18n.TwitchUsername)},
+		{Key: twitchTokenSetting, Label: commons.GetTranslation(commons.I18n.TwitchToken), Placeholder: "oauth:", Secret: true},
+		{Key: twitchServerSetting, Label: commons.GetTranslation(commons.I18n.TwitchServer), Placeholder: twitchServer},
+	}
+}
+
+// List reads the viewers who entered during the last entry window.
+func (twitchSource) List() (*MembersList, error) {
+	return readStoredList(commons.StructuredData.TwitchEntriesFileName, commons.StructuredData.TwitchTiersFileName)
+}
+
+func (twitchSource) Refresh(ctx context.Context, onEvent func(FetchEvent)) (*FetchResult, error) {
+	return nil, ErrRefreshNotSupported
+}
+
+// EntryKeyword returns the keyword set for the channel, DefaultEntryKeyword if none is set.
+func (twitchSource) EntryKeyword() string {
+	if keyword := sourceSetting(TwitchSourceID, twitchKeywordSetting); keyword != "" {
+		return keyword
+	}
+	return DefaultEntryKeyword
+}
+
+// CollectEntries joins the chat of the channel over IRC and enters every viewer sending the keyword, once.
+// The display name of the viewer is used when the server sends it, otherwise their login.
+func (twitchSource) CollectEntries(ctx context.Context, onEntry func(name string, count int)) (*FetchResult, error) {
+	channel := strings.ToLower(strings.TrimPrefix(sourceSetting(TwitchSourceID, twitchChannelSetting), "#"))
+	if channel == "" {
+		return nil, ErrNoTwitchChannel
+	}
+	server := sourceSetting(TwitchSourceID, twitchServerSetting)
+	if server == "" {
+		server = twitchServer
+	}
+
+	dialer := net.Dialer{Timeout: 15 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Leaving the channel and closing the connection ends the read loop once the entry window is closed.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(conn, "PART #%s\r\n", channel)
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	nick, password := twitchLogin()
+	if password != "" {
+		fmt.Fprintf(conn, "PASS %s\r\n", password)
+	}
+	fmt.Fprintf(conn, "NICK %s\r\n", nick)
+	fmt.Fprint(conn, "CAP REQ :twitch.tv/tags\r\n")
+	fmt.Fprintf(conn, "JOIN #%s\r\n", channel)
+	commons.GetLogger().Printf("Entries of the Twitch channel %s opened on %s", channel, server)
+
+	entries := newChatEntries(Campaign{ID: TwitchSourceID, Name: "Twitch #" + channel}, twitchSource{}.EntryKeyword(), onEntry)
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return entries.store(TwitchSourceID, commons.StructuredData.TwitchEntriesFileName, commons.StructuredData.TwitchTiersFileName), err
+		}
+
+		message := parseIRCMessage(strings.TrimRight(line, "\r\n"))
+		switch message.command {
+		case "PING":
+			fmt.Fprintf(conn, "PONG :%s\r\n", message.trailing)
+		case "NOTICE":
+			if strings.Contains(message.trailing, "authentication failed") || strings.Contains(message.trailing, "Improperly formatted auth") {
+				return nil, ErrTwitchLogin
+			}
+		case "PRIVMSG":
+			if entries.matches(message.trailing) {
+				entries.add(strings.ToLower(message.nick), message.tags["display-name"])
+			}
+		}
+	}
+	return entries.store(TwitchSourceID, commons.StructuredData.TwitchEntriesFileName, commons.StructuredData.TwitchTiersFileName), nil
+}
+
+// twitchLogin returns the nick and the password to log in to the chat with. Without a token, the chat is joined
+// anonymously with a "justinfan" nick, which Twitch allows for reading the chat.
+func twitchLogin() (string, string) {
+	token := sourceSetting(TwitchSourceID, twitchTokenSetting)
+	username := strings.ToLower(sourceSetting(TwitchSourceID, twitchUsernameSetting))
+	if token == "" || username == "" {
+		return fmt.Sprintf("justinfan%d", 10000+rand.Intn(90000)), ""
+	}
+	return username, "oauth:" + strings.TrimPrefix(token, "oauth:")
+}
+
+// ircMessage is a line received from an IRC server: the IRCv3 tags, the nick of the sender,
+// the command and the trailing parameter, the text of a chat message.
+type ircMessage struct {
+	tags     map[string]string
+	nick     string
+	command  string
+	trailing string
+}
+
+// parseIRCMessage splits an IRC line into its parts, see ircMessage.
+func parseIRCMessage(line string) ircMessage {
+	message := ircMessage{tags: map[string]string{}}
+	if strings.HasPrefix(line, "@") {
+		tags, rest, _ := strings.Cut(line[1:], " ")
+		for _, tag := range strings.Split(tags, ";") {
+			key, value, _ := strings.Cut(tag, "=")
+			message.tags[key] = unescapeIRCTag(value)
+		}
+		line = rest
+	}
+	if strings.HasPrefix(line, ":") {
+		prefix, rest, _ := strings.Cut(line[1:], " ")
+		message.nick, _, _ = strings.Cut(prefix, "!")
+		line = rest
+	}
+	line, message.trailing, _ = strings.Cut(line, " :")
+	if fields := strings.Fields(line); len(fields) > 0 {
+		message.command = strings.ToUpper(fields[0])
+	}
+	return message
+}
+
+// unescapeIRCTag returns the value of an IRCv3 tag with its escaped characters restored.
+func unescapeIRCTag(value string) string {
+	return strings.NewReplacer(`\s`, " ", `\:`, ";", `\\`, `\`, `\r`, "\r", `\n`, "\n").Replace(value)
+}
@@ -0,0 +1,253 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeIRCServer is a local IRC server a Twitch source connects to, one connection at a time.
+// Every line the source sends on the current connection is queued in lines.
+type fakeIRCServer struct {
+	listener net.Listener
+	conn     net.Conn
+	lines    chan string
+}
+
+// newFakeIRCServer listens on a local port and points the settings of the Twitch source to it.
+func newFakeIRCServer(t *testing.T) *fakeIRCServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	setSourceSettings(TwitchSourceID, map[string]string{twitchServerSetting: listener.Addr().String()})
+	return &fakeIRCServer{listener: listener}
+}
+
+// accept waits for the source to connect and starts queuing the lines it sends.
+func (s *fakeIRCServer) accept(t *testing.T) {
+	t.Helper()
+	conn, err := s.listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s.conn = conn
+	lines := make(chan string, 100)
+	s.lines = lines
+	go func() {
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- strings.TrimRight(scanner.Text(), "\r")
+		}
+		close(lines)
+	}()
+}
+
+// expect returns the first line sent by the source that starts with the prefix, skipping the lines before it.
+func (s *fakeIRCServer) expect(t *testing.T, prefix string) string {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				t.Fatalf("connection closed before %q", prefix)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line
+			}
+		case <-timeout:
+			t.Fatalf("no %q received", prefix)
+		}
+	}
+}
+
+// send sends the lines to the source, then waits until it has read them all, using a PING it answers once it got there.
+func (s *fakeIRCServer) send(t *testing.T, lines string) {
+	t.Helper()
+	if _, err := s.conn.Write([]byte(lines + "PING :sync\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	s.expect(t, "PONG :sync")
+}
+
+// collectTwitchEntries runs CollectEntries in the background and returns the channels of its outcome.
+func collectTwitchEntries(ctx context.Context) (<-chan *FetchResult, <-chan error) {
+	results := make(chan *FetchResult, 1)
+	errs := make(chan error, 1)
+	go func() {
+		result, err := twitchSource{}.CollectEntries(ctx, nil)
+		results <- result
+		errs <- err
+	}()
+	return results, errs
+}
+
+func TestTwitchCollectEntries(t *testing.T) {
+	setupTestData(t)
+	server := newFakeIRCServer(t)
+	setSourceSettings(TwitchSourceID, map[string]string{twitchChannelSetting: "#PickABro"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results, errs := collectTwitchEntries(ctx)
+	server.accept(t)
+
+	if nick := server.expect(t, "NICK "); !strings.HasPrefix(nick, "NICK justinfan") {
+		t.Errorf("anonymous login with %q, want a justinfan nick", nick)
+	}
+	server.expect(t, "JOIN #pickabro")
+	server.send(t, string(readFixture(t, "twitch_chat.irc")))
+
+	cancel()
+	server.expect(t, "PART #pickabro")
+	result, err := <-results, <-errs
+	if err != nil {
+		t.Fatalf("closing the entries returned %v", err)
+	}
+
+	if names, want := memberNames(result.Members), []string{"Alice", "carol", "Erin the Bold"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("entered %v, want %v", names, want)
+	}
+	if id := result.Members[2].ID; id != "twitch:erin" {
+		t.Errorf("viewer ID %q, want the lowercased login twitch:erin", id)
+	}
+	stored, err := twitchSource{}.List()
+	if err != nil || len(stored.PatreonMembers) != 3 || stored.PatreonMembers[0].Source != TwitchSourceID {
+		t.Errorf("stored list %v, %v", stored, err)
+	}
+	if ids := GetSelectedSourceIDs(); !reflect.DeepEqual(ids, []string{PatreonSourceID, TwitchSourceID}) {
+		t.Errorf("sources of the draw %v, want Twitch added", ids)
+	}
+}
+
+func TestTwitchCustomKeyword(t *testing.T) {
+	setupTestData(t)
+	server := newFakeIRCServer(t)
+	setSourceSettings(TwitchSourceID, map[string]string{twitchChannelSetting: "pickabro", twitchKeywordSetting: "!giveaway"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results, errs := collectTwitchEntries(ctx)
+	server.accept(t)
+	server.expect(t, "JOIN #pickabro")
+	server.send(t, string(readFixture(t, "twitch_chat.irc"))+
+		"@display-name=Frank :frank!frank@frank.tmi.twitch.tv PRIVMSG #pickabro :!Giveaway\r\n")
+
+	cancel()
+	result, err := <-results, <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, want := memberNames(result.Members), []string{"Frank"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("entered %v, want %v", names, want)
+	}
+}
+
+func TestTwitchNoEntrants(t *testing.T) {
+	setupTestData(t)
+	server := newFakeIRCServer(t)
+	setSourceSettings(TwitchSourceID, map[string]string{twitchChannelSetting: "pickabro"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results, errs := collectTwitchEntries(ctx)
+	server.accept(t)
+	server.expect(t, "JOIN #pickabro")
+	server.send(t, string(readFixture(t, "twitch_chat.irc")))
+	cancel()
+	if _, err := <-results, <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	// A second entry window nobody enters replaces the viewers of the first one
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	results, errs = collectTwitchEntries(ctx)
+	server.accept(t)
+	server.expect(t, "JOIN #pickabro")
+	server.send(t, "@display-name=Alice :alice!alice@alice.tmi.twitch.tv PRIVMSG #pickabro :hello\r\n")
+	cancel()
+	result, err := <-results, <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Members) != 0 {
+		t.Errorf("entered %v, want nobody", memberNames(result.Members))
+	}
+	stored, err := twitchSource{}.List()
+	if err != nil || len(stored.PatreonMembers) != 0 {
+		t.Errorf("stored list %v, %v, want the viewers of the first window dropped", stored, err)
+	}
+}
+
+func TestTwitchConnectionLost(t *testing.T) {
+	setupTestData(t)
+	server := newFakeIRCServer(t)
+	setSourceSettings(TwitchSourceID, map[string]string{twitchChannelSetting: "pickabro"})
+
+	results, errs := collectTwitchEntries(context.Background())
+	server.accept(t)
+	server.expect(t, "JOIN #pickabro")
+	server.send(t, "@display-name=Alice :alice!alice@alice.tmi.twitch.tv PRIVMSG #pickabro :!enter\r\n")
+	server.conn.Close()
+
+	result, err := <-results, <-errs
+	if err == nil {
+		t.Error("a lost connection returned no error")
+	}
+	if result == nil || len(result.Members) != 1 {
+		t.Fatalf("a lost connection returned %v, want the entries so far", result)
+	}
+}
+
+func TestTwitchLoginRefused(t *testing.T) {
+	setupTestData(t)
+	server := newFakeIRCServer(t)
+	setSourceSettings(TwitchSourceID, map[string]string{
+		twitchChannelSetting:  "pickabro",
+		twitchUsernameSetting: "PickABroBot",
+		twitchTokenSetting:    "wrong",
+	})
+
+	results, errs := collectTwitchEntries(context.Background())
+	server.accept(t)
+	if pass := server.expect(t, "PASS "); pass != "PASS oauth:wrong" {
+		t.Errorf("login with %q, want PASS oauth:wrong", pass)
+	}
+	if nick := server.expect(t, "NICK "); nick != "NICK pickabrobot" {
+		t.Errorf("login with %q, want NICK pickabrobot", nick)
+	}
+	server.conn.Write([]byte(":tmi.twitch.tv NOTICE * :Login authentication failed\r\n"))
+
+	if _, err := <-results, <-errs; !errors.Is(err, ErrTwitchLogin) {
+		t.Errorf("refused login returned %v, want ErrTwitchLogin", err)
+	}
+}
+
+func TestTwitchNoChannel(t *testing.T) {
+	setupTestData(t)
+	if _, err := (twitchSource{}).CollectEntries(context.Background(), nil); !errors.Is(err, ErrNoTwitchChannel) {
+		t.Errorf("CollectEntries without channel returned %v, want ErrNoTwitchChannel", err)
+	}
+}
+
+func TestParseIRCMessage(t *testing.T) {
+	message := parseIRCMessage(`@display-name=Dave\sthe\sGreat;user-id=104 :dave!dave@dave.tmi.twitch.tv PRIVMSG #pickabro :!enter now`)
+	want := ircMessage{
+		tags:     map[string]string{"display-name": "Dave the Great", "user-id": "104"},
+		nick:     "dave",
+		command:  "PRIVMSG",
+		trailing: "!enter now",
+	}
+	if !reflect.DeepEqual(message, want) {
+		t.Errorf("parsed %+v, want %+v", message, want)
+	}
+}
@@ -0,0 +1,190 @@
+package data
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// xlsxWorkbook is the part of xl/workbook.xml listing the sheets.
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRelationships is the content of xl/_rels/workbook.xml.rels, mapping relationship IDs to the sheet files.
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a string item of the shared strings or an inline string, either plain or made of rich text runs.
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// xlsxSharedStrings is the content of xl/sharedStrings.xml.
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxSheet is the part of a worksheet holding the cell values.
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX reads the cell values of the first sheet of an XLSX workbook, row by row.
+// Only the values are read: formulas give their last computed value, and dates their serial number.
+func readXLSX(filePath string) ([][]string, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedStrings := &xlsxSharedStrings{}
+	if file, found := files["xl/sharedStrings.xml"]; found {
+		if err := decodeZipXML(file, sharedStrings); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet := &xlsxSheet{}
+	if err := decodeZipXML(files[sheetPath], sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, sheetRow := range sheet.Rows {
+		row := []string{}
+		for i, cell := range sheetRow.Cells {
+			column := i
+			if refColumn := xlsxColumn(cell.Ref); refColumn >= 0 {
+				column = refColumn
+			}
+			for len(row) <= column {
+				row = append(row, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(sharedStrings.Items) {
+					return nil, fmt.Errorf("cell %s refers to a missing shared string", cell.Ref)
+				}
+				row[column] = sharedStrings.Items[index].String()
+			case "inlineStr":
+				row[column] = cell.Inline.String()
+			default:
+				row[column] = cell.Value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// firstSheetPath returns the path in the archive of the first sheet listed in the workbook.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	workbook := &xlsxWorkbook{}
+	relationships := &xlsxRelationships{}
+	workbookFile, found := files["xl/workbook.xml"]
+	if !found {
+		return "", errors.New("not an XLSX workbook")
+	}
+	if err := decodeZipXML(workbookFile, workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errors.New("the workbook has no sheets")
+	}
+
+	if relationshipsFile, found := files["xl/_rels/workbook.xml.rels"]; found {
+		if err := decodeZipXML(relationshipsFile, relationships); err != nil {
+			return "", err
+		}
+	}
+	for _, relationship := range relationships.Relationships {
+		if relationship.ID != workbook.Sheets[0].RID {
+			continue
+		}
+		sheetPath := path.Join("xl", relationship.Target)
+		if strings.HasPrefix(relationship.Target, "/") {
+			sheetPath = strings.TrimPrefix(relationship.Target, "/")
+		}
+		if _, found := files[sheetPath]; found {
+			return sheetPath, nil
+		}
+	}
+
+	if _, found := files["xl/worksheets/sheet1.xml"]; found {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	return "", fmt.Errorf("sheet %s not found in the workbook", workbook.Sheets[0].Name)
+}
+
+// decodeZipXML decodes the XML file of the archive into v.
+func decodeZipXML(file *zip.File, v interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(reader, 256<<20)).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// xlsxColumn returns the zero based column index of a cell reference, e.g. 27 for AB3.
+func xlsxColumn(ref string) int {
+	column := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+	}
+	return column - 1
+}
@@ -0,0 +1,261 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"pick-a-bro/internal/commons"
+	"strings"
+	"time"
+)
+
+// YouTubeSourceID is the ID of the source of the viewers entering from the YouTube live chat.
+const YouTubeSourceID = "youtube"
+
+// youtubeAPI is the YouTube Data API, used unless another URL is set, e.g. a local server replaying recorded chat messages.
+const youtubeAPI = "https://www.googleapis.com/youtube/v3"
+
+// youtubePollingInterval is the time between two polls of the chat messages when the API does not set one.
+const youtubePollingInterval = 5 * time.Second
+
+// Settings of the YouTube source, see SourceSettingKey
+const (
+	youtubeVideoSetting   = "video"
+	youtubeKeywordSetting = "keyword"
+	youtubeAPIKeySetting  = "apiKey"
+	youtubeAPISetting     = "api"
+)
+
+var ErrNoYouTubeVideo = errors.New("no YouTube video set")
+var ErrNoYouTubeAPIKey = errors.New("no YouTube API key set")
+var ErrNoLiveChat = errors.New("the YouTube video has no active live chat")
+
+// youtubeVideosResponse is the part of the response of the videos API the live chat of a stream is read from.
+type youtubeVideosResponse struct {
+	Items []struct {
+		Snippet struct {
+			Title string `json:"title"`
+		} `json:"snippet"`
+		LiveStreamingDetails struct {
+			ActiveLiveChatID string `json:"activeLiveChatId"`
+		} `json:"liveStreamingDetails"`
+	} `json:"items"`
+}
+
+// youtubeChatResponse is a page of the live chat messages API: the messages, the token of the next page,
+// the time to wait before asking for it and, once the stream is over, the time the chat went offline.
+type youtubeChatResponse struct {
+	NextPageToken         string `json:"nextPageToken"`
+	PollingIntervalMillis int    `json:"pollingIntervalMillis"`
+	OfflineAt             string `json:"offlineAt"`
+	Items                 []struct {
+		Snippet struct {
+			Type           string `json:"type"`
+			DisplayMessage string `json:"displayMessage"`
+		} `json:"snippet"`
+		AuthorDetails struct {
+			ChannelID   string `json:"channelId"`
+			DisplayName string `json:"displayName"`
+		} `json:"authorDetails"`
+	} `json:"items"`
+}
+
+// youtubeErrorResponse is the error returned by the YouTube Data API.
+type youtubeErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// youtubeSource provides the viewers who entered the draw by typing the keyword in the live chat of a YouTube stream
+// while the entry window was open. The chat is read with an API key of the YouTube Data API.
+type youtubeSource struct{}
+
+func (youtubeSource) ID() string {
+	return YouTubeSourceID
+}
+
+func (youtubeSource) Name() string {
+	return "YouTube"
+}
+
+func (youtubeSource) Capabilities() SourceCapabilities {
+	return SourceCapabilities{}
+}
+
+func (youtubeSource) ConfigFields() []SourceConfigField {
+	return []SourceConfigField{
+		{Key: youtubeVideoSetting, Label: commons.GetTranslation(commons.I18n.YouTubeVideo), Placeholder: "https://www.youtube.com/watch?v="},
+		{Key: youtubeKeywordSetting, Label: commons.GetTranslation(commons.I18n.EntryKeyword), Placeholder: DefaultEntryKeyword},
+		{Key: youtubeAPIKeySetting, Label: commons.GetTranslation(commons.I18n.YouTubeAPIKey), Secret: true},
+		{Key: youtubeAPISetting, Label: commons.GetTranslation(commons.I18n.YouTubeAPI), Placeholder: youtubeAPI},
+	}
+}
+
+// List reads the viewers who entered during the last entry window.
+func (youtubeSource) List() (*MembersList, error) {
+	return readStoredList(commons.StructuredData.YouTubeEntriesFileName, commons.StructuredData.YouTubeTiersFileName)
+}
+
+func (youtubeSource) Refresh(ctx context.Context, onEvent func(FetchEvent)) (*FetchResult, error) {
+	return nil, ErrRefreshNotSupported
+}
+
+// EntryKeyword returns the keyword set for the stream, DefaultEntryKeyword if none is set.
+func (youtubeSource) EntryKeyword() string {
+	if keyword := sourceSetting(YouTubeSourceID, youtubeKeywordSetting); keyword != "" {
+		return keyword
+	}
+	return DefaultEntryKeyword
+}
+
+// CollectEntries looks up the live chat of the video, then polls its messages at the interval the API asks for
+// and enters every viewer sending the keyword, once, recognised by their channel ID.
+// The first page holds the messages sent before the entry window opened, so only its token is kept.
+// The entries also close when the stream goes offline.
+func (youtubeSource) CollectEntries(ctx context.Context, onEntry func(name string, count int)) (*FetchResult, error) {
+	client := &youtubeClient{
+		api:        strings.TrimSuffix(sourceSetting(YouTubeSourceID, youtubeAPISetting), "/"),
+		key:        sourceSetting(YouTubeSourceID, youtubeAPIKeySetting),
+		httpClient: &http.Client{Transport: newRetryTransport(http.DefaultTransport)},
+	}
+	if client.api == "" {
+		client.api = youtubeAPI
+	}
+	videoID := youtubeVideoID(sourceSetting(YouTubeSourceID, youtubeVideoSetting))
+	if videoID == "" {
+		return nil, ErrNoYouTubeVideo
+	}
+	if client.key == "" {
+		return nil, ErrNoYouTubeAPIKey
+	}
+
+	liveChatID, title, err := client.liveChat(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	commons.GetLogger().Printf("Entries of the YouTube live chat of %s opened on %s", videoID, client.api)
+
+	entries := newChatEntries(Campaign{ID: YouTubeSourceID, Name: "YouTube: " + title}, youtubeSource{}.EntryKeyword(), onEntry)
+	store := func() *FetchResult {
+		return entries.store(YouTubeSourceID, commons.StructuredData.YouTubeEntriesFileName, commons.StructuredData.YouTubeTiersFileName)
+	}
+
+	pageToken := ""
+	for backlog := true; ; backlog = false {
+		page, err := client.chatMessages(ctx, liveChatID, pageToken)
+		if err != nil {
+			if ctx.Err() != nil {
+				return store(), nil
+			}
+			return store(), err
+		}
+		for _, item := range page.Items {
+			if !backlog && item.Snippet.Type == "textMessageEvent" && entries.matches(item.Snippet.DisplayMessage) {
+				entries.add(item.AuthorDetails.ChannelID, item.AuthorDetails.DisplayName)
+			}
+		}
+		if page.OfflineAt != "" {
+			commons.GetLogger().Printf("The YouTube live chat of %s went offline", videoID)
+			return store(), nil
+		}
+		if page.NextPageToken != "" {
+			pageToken = page.NextPageToken
+		}
+
+		interval := time.Duration(page.PollingIntervalMillis) * time.Millisecond
+		if interval <= 0 {
+			interval = youtubePollingInterval
+		}
+		select {
+		case <-ctx.Done():
+			return store(), nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// youtubeVideoID returns the ID of the video of a YouTube link, or the setting itself if it is not a link.
+// Watch, live, short and youtu.be links are recognised.
+func youtubeVideoID(video string) string {
+	parsed, err := url.Parse(video)
+	if err != nil || parsed.Host == "" {
+		return video
+	}
+	if id := parsed.Query().Get("v"); id != "" {
+		return id
+	}
+	path := strings.Trim(parsed.Path, "/")
+	for _, prefix := range []string{"live/", "shorts/", "embed/"} {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	return path
+}
+
+// youtubeClient sends the requests of the YouTube Data API with an API key.
+type youtubeClient struct {
+	api        string
+	key        string
+	httpClient *http.Client
+}
+
+// liveChat returns the ID of the active live chat of the video and the title of the video.
+func (c *youtubeClient) liveChat(ctx context.Context, videoID string) (string, string, error) {
+	var videos youtubeVideosResponse
+	if err := c.get(ctx, "videos", url.Values{"part": {"snippet,liveStreamingDetails"}, "id": {videoID}}, &videos); err != nil {
+		return "", "", err
+	}
+	if len(videos.Items) == 0 || videos.Items[0].LiveStreamingDetails.ActiveLiveChatID == "" {
+		return "", "", ErrNoLiveChat
+	}
+	return videos.Items[0].LiveStreamingDetails.ActiveLiveChatID, videos.Items[0].Snippet.Title, nil
+}
+
+// chatMessages returns the page of the live chat messages with the given token, the first page if the token is empty.
+func (c *youtubeClient) chatMessages(ctx context.Context, liveChatID string, pageToken string) (*youtubeChatResponse, error) {
+	query := url.Values{"part": {"snippet,authorDetails"}, "liveChatId": {liveChatID}, "maxResults": {"2000"}}
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+	page := &youtubeChatResponse{}
+	if err := c.get(ctx, "liveChat/messages", query, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// get sends a GET request to the endpoint of the API and decodes the response into target.
+// An error response is returned as an error with the message of the API.
+func (c *youtubeClient) get(ctx context.Context, endpoint string, query url.Values, target any) error {
+	query.Set("key", c.key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s?%s", c.api, endpoint, query.Encode()), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("YouTube request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read YouTube response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiError youtubeErrorResponse
+		if json.Unmarshal(body, &apiError) == nil && apiError.Error.Message != "" {
+			return fmt.Errorf("YouTube request failed with status %d: %s", resp.StatusCode, apiError.Error.Message)
+		}
+		return fmt.Errorf("YouTube request failed with status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("failed to parse YouTube response: %w", err)
+	}
+	return nil
+}
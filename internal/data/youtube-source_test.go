@@ -0,0 +1,190 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newYouTubeServer starts a local YouTube Data API serving the given response files: the videos response,
+// and the chat messages pages by page token, the first page under the empty token.
+// The settings of the YouTube source are pointed to it.
+func newYouTubeServer(t *testing.T, videos string, pages map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.URL.Query().Get("key"); key != "test-key" {
+			t.Errorf("request with API key %q", key)
+		}
+		switch r.URL.Path {
+		case "/videos":
+			if id := r.URL.Query().Get("id"); id != "abc123" {
+				t.Errorf("videos requested for %q, want abc123", id)
+			}
+			w.Write(readFixture(t, videos))
+		case "/liveChat/messages":
+			if chat := r.URL.Query().Get("liveChatId"); chat != "chat-1" {
+				t.Errorf("messages requested for %q, want chat-1", chat)
+			}
+			page, found := pages[r.URL.Query().Get("pageToken")]
+			if !found {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(readFixture(t, page))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	setSourceSettings(YouTubeSourceID, map[string]string{
+		youtubeVideoSetting:  "https://www.youtube.com/watch?v=abc123",
+		youtubeAPIKeySetting: "test-key",
+		youtubeAPISetting:    server.URL + "/",
+	})
+	return server
+}
+
+func TestYouTubeCollectEntries(t *testing.T) {
+	setupTestData(t)
+	newYouTubeServer(t, "youtube_videos.json", map[string]string{
+		"":       "youtube_chat_1.json",
+		"page-2": "youtube_chat_2.json",
+		"page-3": "youtube_chat_3.json",
+	})
+
+	counts := []int{}
+	result, err := youtubeSource{}.CollectEntries(context.Background(), func(name string, count int) {
+		counts = append(counts, count)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first page holds the messages sent before the window opened, the superchat is no text message,
+	// and the two Alices are told apart by their channel
+	if names, want := memberNames(result.Members), []string{"Alice", "Alice", "Carol"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("entered %v, want %v", names, want)
+	}
+	if ids := []string{result.Members[0].ID, result.Members[1].ID}; ids[0] != "youtube:UC-alice" || ids[1] != "youtube:UC-other-alice" {
+		t.Errorf("viewer IDs %v", ids)
+	}
+	if !reflect.DeepEqual(counts, []int{1, 2, 3}) {
+		t.Errorf("entries reported as %v", counts)
+	}
+	if result.Campaigns[0].Name != "YouTube: Pick a Bro live" {
+		t.Errorf("entries named %q", result.Campaigns[0].Name)
+	}
+	stored, err := youtubeSource{}.List()
+	if err != nil || len(stored.PatreonMembers) != 3 {
+		t.Errorf("stored list %v, %v", stored, err)
+	}
+}
+
+func TestYouTubeNoEntrants(t *testing.T) {
+	setupTestData(t)
+	newYouTubeServer(t, "youtube_videos.json", map[string]string{
+		"":       "youtube_chat_1.json",
+		"page-2": "youtube_chat_2.json",
+		"page-3": "youtube_chat_3.json",
+	})
+	if _, err := (youtubeSource{}).CollectEntries(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second stream nobody enters, going offline after a single message, replaces the viewers of the first one
+	newYouTubeServer(t, "youtube_videos.json", map[string]string{
+		"":       "youtube_chat_1.json",
+		"page-2": "youtube_chat_offline.json",
+	})
+	result, err := youtubeSource{}.CollectEntries(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Members) != 0 {
+		t.Errorf("entered %v, want nobody", memberNames(result.Members))
+	}
+	stored, err := youtubeSource{}.List()
+	if err != nil || len(stored.PatreonMembers) != 0 {
+		t.Errorf("stored list %v, %v, want the viewers of the first stream dropped", stored, err)
+	}
+}
+
+func TestYouTubeCancel(t *testing.T) {
+	setupTestData(t)
+	newYouTubeServer(t, "youtube_videos.json", map[string]string{
+		"":       "youtube_chat_1.json",
+		"page-2": "youtube_chat_2.json",
+		"page-3": "youtube_chat_2.json",
+		"page-4": "youtube_chat_2.json",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result, err := youtubeSource{}.CollectEntries(ctx, func(name string, count int) {
+		if count == 2 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("closing the entries returned %v", err)
+	}
+	if len(result.Members) != 2 {
+		t.Errorf("entered %v, want the two viewers of the page read", memberNames(result.Members))
+	}
+}
+
+func TestYouTubeErrorResponse(t *testing.T) {
+	setupTestData(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(readFixture(t, "youtube_quota_error.json"))
+	}))
+	defer server.Close()
+	setSourceSettings(YouTubeSourceID, map[string]string{youtubeVideoSetting: "abc123", youtubeAPIKeySetting: "test-key", youtubeAPISetting: server.URL})
+
+	_, err := youtubeSource{}.CollectEntries(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "status 403: The request cannot be completed because you have exceeded your quota.") {
+		t.Errorf("error response returned %v", err)
+	}
+}
+
+func TestYouTubeNoLiveChat(t *testing.T) {
+	setupTestData(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items": [{"snippet": {"title": "Recorded video"}, "liveStreamingDetails": {}}]}`))
+	}))
+	defer server.Close()
+	setSourceSettings(YouTubeSourceID, map[string]string{youtubeVideoSetting: "abc123", youtubeAPIKeySetting: "test-key", youtubeAPISetting: server.URL})
+
+	if _, err := (youtubeSource{}).CollectEntries(context.Background(), nil); !errors.Is(err, ErrNoLiveChat) {
+		t.Errorf("video without live chat returned %v, want ErrNoLiveChat", err)
+	}
+}
+
+func TestYouTubeMissingSettings(t *testing.T) {
+	setupTestData(t)
+	if _, err := (youtubeSource{}).CollectEntries(context.Background(), nil); !errors.Is(err, ErrNoYouTubeVideo) {
+		t.Errorf("no video returned %v, want ErrNoYouTubeVideo", err)
+	}
+	setSourceSettings(YouTubeSourceID, map[string]string{youtubeVideoSetting: "abc123"})
+	if _, err := (youtubeSource{}).CollectEntries(context.Background(), nil); !errors.Is(err, ErrNoYouTubeAPIKey) {
+		t.Errorf("no API key returned %v, want ErrNoYouTubeAPIKey", err)
+	}
+}
+
+func TestYouTubeVideoID(t *testing.T) {
+	for link, want := range map[string]string{
+		"abc123":                                   "abc123",
+		"https://www.youtube.com/watch?v=abc123":   "abc123",
+		"https://www.youtube.com/live/abc123?si=x": "abc123",
+		"https://youtu.be/abc123":                  "abc123",
+		"https://www.youtube.com/shorts/abc123":    "abc123",
+	} {
+		if id := youtubeVideoID(link); id != want {
+			t.Errorf("youtubeVideoID(%q) = %q, want %q", link, id, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package lottery
+
+import (
+	"image/color"
+	"pick-a-bro/internal/commons"
+	"pick-a-bro/internal/data"
+)
+
+// DrawResult is the outcome of a draw: the people of the pool in the order they are shown on the board, one tile each,
+// the chance of every one of them to be drawn first, the colors of their tiers,
+// and the winners in the order they were drawn with their index in the pool.
+// The winners are picked when the draw is made, so showing the board can neither change nor break the outcome.
+type DrawResult struct {
+	Pool          []data.PatreonMember
+	Probabilities []float64
+	ColorCode     map[string]color.Color
+	Winners       []data.PatreonMember
+	WinnerIndices []int
+}
+
+// GetNumberOfWinners returns the number of winners of a draw stored in the preferences, at least 1.
+func GetNumberOfWinners() int {
+	if winners := commons.GetPreferences().IntWithFallback(commons.NumberOfWinners, 1); winners > 1 {
+		return winners
+	}
+	return 1
+}
+
+// Draw prepares the weighted pool of the loaded members list, see prepareLottery, and draws the number of winners set in the preferences.
+// Every winner is drawn among the people left with a chance proportional to their weight, so the winners are distinct people,
+// see WeightedPool.pick. If fewer people take part than the number of winners, every one of them wins.
+// It returns ErrEmptyPool if no participant is left to draw.
+func Draw() (*DrawResult, error) {
+	membersList, pool := prepareLottery()
+	if pool.Len() == 0 {
+		return nil, ErrEmptyPool
+	}
+
+	result := &DrawResult{Pool: pool.People, Probabilities: pool.Probabilities(), ColorCode: membersList.ColorCode}
+	for _, index := range pool.pick(GetNumberOfWinners()) {
+		result.Winners = append(result.Winners, pool.People[index])
+		result.WinnerIndices = append(result.WinnerIndices, index)
+	}
+	return result, nil
+}
+
+// Path returns the indices of the board tiles the highlight visits while revealing the winner of the given place,
+// one per step, ending on the winning tile. Consecutive steps never stay on the same tile, unless the board has a single tile.
+func (result *DrawResult) Path(place int, steps int) []int {
+	path := make([]int, steps)
+	if steps == 0 {
+		return path
+	}
+	path[steps-1] = result.WinnerIndices[place]
+	for i := steps - 2; i >= 0; i-- {
+		path[i] = r.Intn(len(result.Pool))
+		for len(result.Pool) > 1 && path[i] == path[i+1] {
+			path[i] = r.Intn(len(result.Pool))
+		}
+	}
+	return path
+}
@@ -4,45 +4,74 @@ import (
 	"encoding/json"
 	"os"
 	"pick-a-bro/internal/commons"
+	"pick-a-bro/internal/data"
 	"time"
 )
 
+// Winner is a previous winner. Key recognises the person, see data.PersonKey; winners recorded by older versions have none.
+// Override tells whether the winner was added or edited by the operator, empty otherwise.
+// Within a draw entry, the date is the one of the draw.
 type Winner struct {
 	FullName string
+	Key      string `json:",omitempty"`
+	DateTime string `json:",omitempty"`
+	Override string `json:",omitempty"`
+}
+
+// DrawEntry is an entry of the winners history: the date of a draw and its winners, in the order they were drawn.
+type DrawEntry struct {
 	DateTime string
+	Winners  []Winner
 }
 
+// Winners is the content of the winners file. Older versions stored a winner per draw in Winners;
+// they are moved to Draws when the file is read.
 type Winners struct {
-	Winners []Winner `json:"winners"`
+	Winners []Winner    `json:"winners,omitempty"`
+	Draws   []DrawEntry `json:"draws"`
 }
 
-// AddToWinnersList adds a new winner to the list of previous winners.
-// It takes the name of the winner as a parameter and appends it to the list.
-// The updated list is then written back to the file.
-func AddToWinnersList(winner string) {
-	newWinner := createWinner(winner)
-	winners := readWinnersFromFile()
+// AddToWinnersList adds a draw to the winners history.
+// It takes the winning members, in the order they were drawn, and records them in a single entry, each marked if the operator added or edited it.
+// The updated history is then written back to the file.
+func AddToWinnersList(winners []data.PatreonMember) {
+	entry := DrawEntry{DateTime: time.Now().Format("02/01/2006 15:04:05"), Winners: []Winner{}}
+	for _, winner := range winners {
+		entry.Winners = append(entry.Winners, createWinner(winner))
+	}
+	history := readWinnersFromFile()
 
-	winners.Winners = append(winners.Winners, newWinner)
+	history.Draws = append(history.Draws, entry)
 
-	writeWinnersToFile(winners)
+	writeWinnersToFile(history)
 }
 
-func GetWinnersList() []Winner {
-	winners := readWinnersFromFile()
+// GetDraws returns the entries of the winners history, oldest first.
+func GetDraws() []DrawEntry {
+	return readWinnersFromFile().Draws
+}
 
-	return winners.Winners
+// GetWinnersList returns the winners of every draw of the history, each with the date of their draw.
+func GetWinnersList() []Winner {
+	winners := []Winner{}
+	for _, draw := range GetDraws() {
+		for _, winner := range draw.Winners {
+			winner.DateTime = draw.DateTime
+			winners = append(winners, winner)
+		}
+	}
+	return winners
 }
 
-func createWinner(name string) Winner {
-	return Winner{FullName: name, DateTime: time.Now().Format("02/01/2006 15:04:05")}
+func createWinner(member data.PatreonMember) Winner {
+	return Winner{FullName: member.FullName, Key: data.PersonKey(member), Override: member.Override}
 }
 
 // readWinnersFromFile reads the previous winners from a file and returns them.
 // If the file does not exist or there is an error reading the file, an empty
-// Winners struct is returned.
+// Winners struct is returned. The winners of a file written by an older version become a draw entry each.
 func readWinnersFromFile() Winners {
-	filename := commons.StructuredData.WinnersFileName
+	filename := commons.GetDataPath(commons.StructuredData.WinnersFileName)
 
 	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
@@ -53,8 +82,12 @@ func readWinnersFromFile() Winners {
 	var winners Winners
 	err = json.NewDecoder(file).Decode(&winners)
 	if err != nil {
-		winners.Winners = []Winner{}
+		winners = Winners{}
+	}
+	for _, legacy := range winners.Winners {
+		winners.Draws = append(winners.Draws, DrawEntry{DateTime: legacy.DateTime, Winners: []Winner{{FullName: legacy.FullName, Override: legacy.Override}}})
 	}
+	winners.Winners = nil
 
 	return winners
 }
@@ -64,7 +97,7 @@ func readWinnersFromFile() Winners {
 // The function marshals the winners data into JSON format and writes it to the file specified by the `WinnersFileName` field in the `StructuredData` struct.
 // If any error occurs during the marshaling or writing process, the function logs a fatal error using the logger from the `commons` package.
 func writeWinnersToFile(winners Winners) {
-	filename := commons.StructuredData.WinnersFileName
+	filename := commons.GetDataPath(commons.StructuredData.WinnersFileName)
 
 	jsonData, err := json.Marshal(winners)
 	if err != nil {
@@ -79,7 +112,7 @@ func writeWinnersToFile(winners Winners) {
 
 // ClearWinnersList removes the file containing the list of previous winners.
 func ClearWinnersList() {
-	filename := commons.StructuredData.WinnersFileName
+	filename := commons.GetDataPath(commons.StructuredData.WinnersFileName)
 	err := os.Remove(filename)
 	if err != nil {
 		commons.GetLogger().Fatalf("Failed removing file: %s", err)
@@ -0,0 +1,109 @@
+package lottery
+
+import (
+	"pick-a-bro/internal/data"
+	"sort"
+)
+
+// WeightedPool holds every person taking part in a draw once, with the weight of their chances.
+// A person's chance to win is their weight over the total weight of the pool, so weights need not be whole numbers.
+type WeightedPool struct {
+	People  []data.PatreonMember
+	Weights []float64
+	index   map[string]int
+}
+
+// newWeightedPool creates an empty pool.
+func newWeightedPool() *WeightedPool {
+	return &WeightedPool{People: []data.PatreonMember{}, Weights: []float64{}, index: map[string]int{}}
+}
+
+// add adds the member with the given weight. A person already in the pool, recognised by data.PersonKey,
+// keeps a single place and gets the weight added to theirs. A weight that is not positive is left out.
+func (pool *WeightedPool) add(member data.PatreonMember, weight float64) {
+	if weight <= 0 {
+		return
+	}
+	key := data.PersonKey(member)
+	if i, found := pool.index[key]; found {
+		pool.Weights[i] += weight
+		return
+	}
+	pool.index[key] = len(pool.People)
+	pool.People = append(pool.People, member)
+	pool.Weights = append(pool.Weights, weight)
+}
+
+// remove leaves out the people the keep function returns false for.
+func (pool *WeightedPool) remove(keep func(member data.PatreonMember) bool) {
+	filtered := newWeightedPool()
+	for i, member := range pool.People {
+		if keep(member) {
+			filtered.add(member, pool.Weights[i])
+		}
+	}
+	*pool = *filtered
+}
+
+// shuffle puts the people of the pool in a random order.
+func (pool *WeightedPool) shuffle() {
+	r.Shuffle(len(pool.People), func(i, j int) {
+		pool.People[i], pool.People[j] = pool.People[j], pool.People[i]
+		pool.Weights[i], pool.Weights[j] = pool.Weights[j], pool.Weights[i]
+	})
+	for i, member := range pool.People {
+		pool.index[data.PersonKey(member)] = i
+	}
+}
+
+// Len returns the number of people in the pool.
+func (pool *WeightedPool) Len() int {
+	return len(pool.People)
+}
+
+// Probabilities returns the chance of every person of the pool to be drawn first, in pool order. They add up to 1,
+// unless the total weight is 0, in which case nobody can be drawn and every chance is 0.
+func (pool *WeightedPool) Probabilities() []float64 {
+	total := 0.0
+	for _, weight := range pool.Weights {
+		total += weight
+	}
+	probabilities := make([]float64, len(pool.Weights))
+	if total <= 0 {
+		return probabilities
+	}
+	for i, weight := range pool.Weights {
+		probabilities[i] = weight / total
+	}
+	return probabilities
+}
+
+// pick draws the given number of distinct people and returns their indices in the pool, in the order they were drawn.
+// Every person is drawn among the ones left with a chance proportional to their weight: a random point
+// of the cumulative weights is looked up with a binary search, and the weight of the person drawn is then set aside.
+// If the pool has fewer people, every one of them is drawn; people without weight are never drawn.
+func (pool *WeightedPool) pick(count int) []int {
+	weights := append([]float64{}, pool.Weights...)
+	cumulative := make([]float64, len(weights))
+	picked := []int{}
+	for len(picked) < count && len(picked) < len(weights) {
+		total := 0.0
+		for i, weight := range weights {
+			total += weight
+			cumulative[i] = total
+		}
+		if total <= 0 {
+			break
+		}
+
+		point := r.Float64() * total
+		i := sort.Search(len(cumulative), func(i int) bool { return cumulative[i] > point })
+		// Rounding may leave the point on the total; the last person still in the pool takes it.
+		for i == len(cumulative) || weights[i] == 0 {
+			i--
+		}
+		picked = append(picked, i)
+		weights[i] = 0
+	}
+	return picked
+}
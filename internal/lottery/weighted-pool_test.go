@@ -0,0 +1,127 @@
+package lottery
+
+import (
+	"math"
+	"math/rand"
+	"pick-a-bro/internal/data"
+	"sort"
+	"testing"
+)
+
+// newTestPool returns a pool of people named after the weights, in name order, with the random source seeded for repeatable draws.
+func newTestPool(t *testing.T, weights map[string]float64) *WeightedPool {
+	t.Helper()
+	r = rand.New(rand.NewSource(1))
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pool := newWeightedPool()
+	for _, name := range names {
+		pool.add(data.PatreonMember{ID: name, FullName: name, Source: data.PatreonSourceID}, weights[name])
+	}
+	return pool
+}
+
+func TestProbabilitiesMatchWeights(t *testing.T) {
+	pool := newTestPool(t, map[string]float64{"a": 1, "b": 2.5, "c": 0.5})
+
+	sum := 0.0
+	for i, probability := range pool.Probabilities() {
+		if want := pool.Weights[i] / 4; math.Abs(probability-want) > 1e-12 {
+			t.Errorf("probability of %s = %v, want %v", pool.People[i].FullName, probability, want)
+		}
+		sum += probability
+	}
+	if math.Abs(sum-1) > 1e-12 {
+		t.Errorf("probabilities add up to %v, want 1", sum)
+	}
+}
+
+func TestAddMergesWeightsOfAPerson(t *testing.T) {
+	pool := newTestPool(t, nil)
+	member := data.PatreonMember{ID: "1", UserID: "u1", FullName: "Alice", Source: data.PatreonSourceID}
+	pool.add(member, 1)
+	member.ID = "2"
+	pool.add(member, 0.5)
+	pool.add(data.PatreonMember{ID: "3", FullName: "Bob"}, 0)
+	pool.add(data.PatreonMember{ID: "4", FullName: "Carol"}, -1)
+
+	if pool.Len() != 1 || pool.Weights[0] != 1.5 {
+		t.Fatalf("pool = %v with weights %v, want Alice alone with 1.5", pool.People, pool.Weights)
+	}
+}
+
+func TestRemovedPersonIsNeverPicked(t *testing.T) {
+	pool := newTestPool(t, map[string]float64{"a": 1, "b": 100, "c": 1})
+	pool.remove(func(member data.PatreonMember) bool { return member.FullName != "b" })
+
+	if pool.Len() != 2 {
+		t.Fatalf("pool has %d people after the removal, want 2", pool.Len())
+	}
+	for i := 0; i < 1000; i++ {
+		for _, index := range pool.pick(1) {
+			if pool.People[index].FullName == "b" {
+				t.Fatal("a removed person was picked")
+			}
+		}
+	}
+}
+
+func TestPickReturnsDistinctPeople(t *testing.T) {
+	pool := newTestPool(t, map[string]float64{"a": 1, "b": 50, "c": 0.1, "d": 3, "e": 1})
+
+	for _, count := range []int{1, 3, 5, 8} {
+		picked := pool.pick(count)
+		if want := min(count, pool.Len()); len(picked) != want {
+			t.Errorf("pick(%d) returned %d people, want %d", count, len(picked), want)
+		}
+		seen := map[int]bool{}
+		for _, index := range picked {
+			if seen[index] {
+				t.Errorf("pick(%d) returned %s twice", count, pool.People[index].FullName)
+			}
+			seen[index] = true
+		}
+	}
+}
+
+func TestPickFollowsWeights(t *testing.T) {
+	pool := newTestPool(t, map[string]float64{"a": 1, "b": 3})
+
+	const draws = 20000
+	wins := map[string]int{}
+	for i := 0; i < draws; i++ {
+		wins[pool.People[pool.pick(1)[0]].FullName]++
+	}
+	if share := float64(wins["b"]) / draws; math.Abs(share-0.75) > 0.02 {
+		t.Errorf("b won %.3f of the draws, want about 0.75", share)
+	}
+}
+
+func TestEmptyPool(t *testing.T) {
+	pool := newTestPool(t, nil)
+
+	if picked := pool.pick(1); len(picked) != 0 {
+		t.Errorf("pick on an empty pool returned %v", picked)
+	}
+	if probabilities := pool.Probabilities(); len(probabilities) != 0 {
+		t.Errorf("probabilities of an empty pool = %v", probabilities)
+	}
+}
+
+func TestZeroTotalWeight(t *testing.T) {
+	pool := newTestPool(t, map[string]float64{"a": 1, "b": 1})
+	pool.Weights = []float64{0, 0}
+
+	if picked := pool.pick(1); len(picked) != 0 {
+		t.Errorf("pick without weight returned %v", picked)
+	}
+	for _, probability := range pool.Probabilities() {
+		if probability != 0 {
+			t.Errorf("probabilities without weight = %v, want zeros", pool.Probabilities())
+		}
+	}
+}
@@ -1,92 +1,124 @@
 package lottery
 
 import (
+	"errors"
 	"math/rand"
 	"pick-a-bro/internal/commons"
 	"pick-a-bro/internal/data"
+	"strings"
 	"time"
 )
 
 var r = rand.New(rand.NewSource(time.Now().UnixNano()))
 
-func InitMembersList() (*data.MembersList, error) {
-	membersList, err := prepareLottery()
-	if err != nil {
-		return nil, err
-	}
-
-	return membersList, nil
-}
+var ErrEmptyPool = errors.New("no participant left to draw")
 
-// prepareLottery prepares the lottery by retrieving preferences, getting the members list,
-// applying the chances rule, excluding winners if necessary, shuffling the members list,
-// and setting the enhanced members list as the new members list.
-// It returns the original members list and an error, if any.
-func prepareLottery() (*data.MembersList, error) {
+// prepareLottery prepares the pool of the draw from the members list: it leaves out the members removed by the operator,
+// applies the chances rule, excludes the previous winners if necessary and shuffles the pool.
+// Every person of the pool is a tile of the board. The members list itself is left unchanged.
+// It returns the members list and the pool.
+func prepareLottery() (*data.MembersList, *WeightedPool) {
 	preferences := commons.GetPreferences()
 	membersList := data.GetMembersAndTiers()
 
-	chancesRule := preferences.StringWithFallback(commons.ChancesRule, commons.GetTranslation(commons.ChancesRules[0]))
+	chancesRule := GetChancesRule()
 
-	enhancedMembersList := prepareMembersList(chancesRule, membersList.PatreonMembers)
+	pool := prepareMembersList(chancesRule, drawnMembers(membersList.PatreonMembers))
 
 	if preferences.BoolWithFallback(commons.ExcludeWinners, false) {
-		enhancedMembersList = excludeWinners(enhancedMembersList)
+		excludeWinners(pool)
 	}
-	shuffleMembers(enhancedMembersList)
-	data.SetMembersList(enhancedMembersList)
-	return membersList, nil
+	pool.shuffle()
+	return membersList, pool
 }
 
-// prepareMembersList prepares the members list based on the chances rule and returns the updated list.
-// It takes a chancesRule string and a membersList []data.PatreonMember as input parameters.
-// The chancesRule determines how the members list will be prepared.
-// The membersList is the list of Patreon members to be prepared.
-// The function iterates over the membersList and duplicates each member based on the chances rule.
-// If the chancesPerUser is 1, the function returns the original membersList.
-// If the chancesPerUser is greater than 1, the function duplicates each member in the list by the chancesPerUser value.
-// If the chances rule is based on the tier of each member, the function duplicates each member in the list based on the chances value associated with their tier.
-// The function returns the updated membersList.
-func prepareMembersList(chancesRule string, membersList []data.PatreonMember) []data.PatreonMember {
-	switch chancesRule {
-	case commons.GetTranslation(commons.ChancesRules[0]):
-		chancesPerUser := commons.GetPreferences().IntWithFallback(commons.ChancesPerUser, 1)
-		if chancesPerUser == 1 {
-			return membersList
-		}
-		for _, d := range membersList {
-			for i := 1; i < chancesPerUser; i++ {
-				membersList = append(membersList, d)
+// GetChancesRule returns the ID of the chances rule stored in the preferences, the equal chances rule if none is stored.
+// Older versions stored the translated label of the rule; such a value is mapped back to its rule ID,
+// whatever the language it was saved in, and the ID is stored instead.
+func GetChancesRule() string {
+	preferences := commons.GetPreferences()
+	stored := preferences.StringWithFallback(commons.ChancesRule, commons.ChancesRuleIDs.Equal)
+	if _, ok := commons.ChancesRuleLabels[stored]; ok {
+		return stored
+	}
+
+	for _, rule := range commons.ChancesRules {
+		for _, label := range commons.GetAllTranslations(commons.ChancesRuleLabels[rule]) {
+			if label == stored {
+				preferences.SetString(commons.ChancesRule, rule)
+				commons.GetLogger().Printf("Chances rule %q migrated to %s", stored, rule)
+				return rule
 			}
 		}
-	case commons.GetTranslation(commons.ChancesRules[1]):
-		for _, d := range membersList {
-			for i := 1; i < commons.GetPreferences().IntWithFallback("chances"+d.Tier, 1); i++ {
-				membersList = append(membersList, d)
+	}
+
+	commons.GetLogger().Printf("Unknown chances rule %q, using %s", stored, commons.ChancesRuleIDs.Equal)
+	preferences.SetString(commons.ChancesRule, commons.ChancesRuleIDs.Equal)
+	return commons.ChancesRuleIDs.Equal
+}
+
+// prepareMembersList prepares the weighted pool of the members list based on the chances rule and returns it.
+// It takes a chancesRule ID and a membersList []data.PatreonMember as input parameters.
+// The chancesRule determines the weight of every member:
+// with equal chances, every member gets the chancesPerUser value.
+// If the chances rule is based on the tier of each member, every member gets the chances value stored for their tier ID.
+// If the chances rule is based on the campaigns, each member gets the sum of the chances stored for every campaign they support.
+// Chances may be fractions; a member whose chances are 0 or less is left out of the pool. The chances of a participant imported
+// with several entries are multiplied by their number of entries. The stored chances are read once per tier or campaign.
+// The function returns the pool, holding every person once.
+func prepareMembersList(chancesRule string, membersList []data.PatreonMember) *WeightedPool {
+	chancesPerUser := commons.GetPreferences().FloatWithFallback(commons.ChancesPerUser, 1)
+	tierChances := map[string]float64{}
+	campaignChances := map[string]float64{}
+	chancesOf := func(member data.PatreonMember) float64 {
+		switch chancesRule {
+		case commons.ChancesRuleIDs.ByTier:
+			if _, found := tierChances[member.TierID]; !found {
+				tierChances[member.TierID] = data.GetTierChances(member.TierID)
+			}
+			return tierChances[member.TierID]
+		case commons.ChancesRuleIDs.ByCampaign:
+			key := strings.Join(append([]string{member.CampaignID}, member.Campaigns...), ",")
+			if _, found := campaignChances[key]; !found {
+				campaignChances[key] = data.GetMemberCampaignChances(member)
 			}
+			return campaignChances[key]
 		}
+		return chancesPerUser
 	}
 
-	return membersList
+	pool := newWeightedPool()
+	for _, d := range membersList {
+		pool.add(d, chancesOf(d)*float64(d.EntryCount()))
+	}
+	return pool
 }
 
-// excludeWinners removes the winners from the enhancedMembersList.
-// It takes a slice of PatreonMember structs as input and returns a new slice
-// with the winners excluded.
-func excludeWinners(enhancedMembersList []data.PatreonMember) []data.PatreonMember {
-	winners := GetWinnersList()
-	for _, winner := range winners {
-		for i, member := range enhancedMembersList {
-			if member.FullName == winner.FullName {
-				enhancedMembersList = append(enhancedMembersList[:i], enhancedMembersList[i+1:]...)
-			}
+// drawnMembers returns a copy of the members taking part in the draw, without the ones the operator removed.
+func drawnMembers(membersList []data.PatreonMember) []data.PatreonMember {
+	drawn := make([]data.PatreonMember, 0, len(membersList))
+	for _, member := range membersList {
+		if member.Override != data.OverrideRemoved {
+			drawn = append(drawn, member)
 		}
 	}
-	return enhancedMembersList
+	return drawn
 }
 
-func shuffleMembers(membersList []data.PatreonMember) {
-	r.Shuffle(len(membersList), func(i, j int) {
-		membersList[i], membersList[j] = membersList[j], membersList[i]
+// excludeWinners leaves the previous winners out of the pool, recognised by their person key, see data.PersonKey,
+// so people of different sources sharing a name do not exclude each other. Winners recorded by older versions
+// have no key and are recognised by their name.
+func excludeWinners(pool *WeightedPool) {
+	keys := map[string]bool{}
+	names := map[string]bool{}
+	for _, winner := range GetWinnersList() {
+		if winner.Key != "" {
+			keys[winner.Key] = true
+		} else {
+			names[winner.FullName] = true
+		}
+	}
+	pool.remove(func(member data.PatreonMember) bool {
+		return !keys[data.PersonKey(member)] && !names[member.FullName]
 	})
 }
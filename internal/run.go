@@ -2,9 +2,11 @@ package internal
 
 import (
 	"embed"
+	"flag"
 	"log"
 	"os"
 	"pick-a-bro/internal/commons"
+	"pick-a-bro/internal/data"
 	"pick-a-bro/views"
 
 	"fyne.io/fyne/v2"
@@ -24,7 +26,11 @@ var localeFS embed.FS
 var samplesFS embed.FS
 
 // RunApp initializes and runs the Pick a Bro application.
+// The data directory can be set with the -data-dir command-line flag, see commons.ResolveDataDir.
 func RunApp() {
+	dataDirFlag := flag.String("data-dir", "", "directory where the patreons lists, the winners and the logs are stored")
+	flag.Parse()
+
 	// Create a new instance of the Pick a Bro application
 	pickABro := app.NewWithID("cloud.devsinthe.pick-a-bro")
 
@@ -37,6 +43,9 @@ func RunApp() {
 	// Set the application preferences
 	commons.SetPreferences(pickABro.Preferences())
 
+	// Set the data directory and move there the files older versions wrote to the working directory
+	setupDataDir(pickABro, *dataDirFlag)
+
 	// Show the language selection view
 	views.SelectLanguage(mainPanel)
 
@@ -44,9 +53,19 @@ func RunApp() {
 	mainPanel.ShowAndRun()
 }
 
-func init() {
+// setupDataDir resolves and creates the data directory, migrates the files found in the working directory
+// on the first start and opens the log file in the data directory.
+func setupDataDir(pickABro fyne.App, dataDirFlag string) {
+	dataDir := commons.ResolveDataDir(pickABro, dataDirFlag)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	commons.SetDataDir(dataDir)
+
+	moved, errs := data.MigrateWorkingDirFiles()
+
 	// Open a file for writing logs
-	file, err := os.OpenFile("log.txt", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	file, err := os.OpenFile(commons.GetDataPath(commons.LogFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -57,6 +76,19 @@ func init() {
 	// Set the logger for the application
 	commons.SetLogger(logger)
 
+	logger.Printf("Data directory: %s", dataDir)
+	for _, name := range moved {
+		logger.Printf("%s moved from the working directory to the data directory", name)
+	}
+	for _, err := range errs {
+		logger.Print(err)
+	}
+}
+
+func init() {
+	// Log to the standard error until the log file is opened in the data directory
+	commons.SetLogger(log.New(os.Stderr, "", log.LstdFlags))
+
 	// Set the embedded file systems for images, audio, locales, and samples
 	commons.SetImagesFS(&imagesFS)
 	commons.SetAudioFS(&audioFS)
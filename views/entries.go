@@ -0,0 +1,53 @@
+package views
+
+import (
+	"context"
+	"fmt"
+	"pick-a-bro/internal/commons"
+	"pick-a-bro/internal/data"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// openEntries opens the entry window of the source: the participants enter while the window is open,
+// with a live counter of the entries and the name of the last participant who entered.
+// Closing the entries stores the participants, loads the draw list and opens the rules view with a summary.
+// If the connection fails, an error dialog is shown; the entries collected before are kept.
+func openEntries(window fyne.Window, source data.EntrySource) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	countLabel := widget.NewLabelWithStyle(fmt.Sprintf(commons.GetTranslation(commons.I18n.EntriesCount), 0), fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	lastLabel := widget.NewLabel("")
+	content := container.NewVBox(widget.NewLabel(fmt.Sprintf(commons.GetTranslation(commons.I18n.EntriesOpen), source.EntryKeyword())),
+		widget.NewProgressBarInfinite(), countLabel, lastLabel)
+
+	entriesDialog := dialog.NewCustom(fmt.Sprintf(commons.GetTranslation(commons.I18n.OpenEntries), source.Name()),
+		commons.GetTranslation(commons.I18n.CloseEntries), content, window)
+	entriesDialog.Resize(fyne.NewSize(400, 200))
+	entriesDialog.SetOnClosed(cancel)
+	entriesDialog.Show()
+
+	go func() {
+		result, err := source.CollectEntries(ctx, func(name string, count int) {
+			countLabel.SetText(fmt.Sprintf(commons.GetTranslation(commons.I18n.EntriesCount), count))
+			lastLabel.SetText(fmt.Sprintf(commons.GetTranslation(commons.I18n.LastEntry), name))
+		})
+		entriesDialog.Hide()
+		if result != nil && len(result.Members) > 0 {
+			data.ExtractDataFromFile()
+			SetRules(window)
+			dialog.NewCustom(commons.GetTranslation(commons.I18n.Success), commons.GetTranslation(commons.I18n.Close),
+				createFetchSummary(fmt.Sprintf(commons.GetTranslation(commons.I18n.EntriesSummary), len(result.Members)), result), window).Show()
+		} else if result != nil && err == nil {
+			data.ExtractDataFromFile()
+			dialog.NewInformation(commons.GetTranslation(commons.I18n.MissingData), commons.GetTranslation(commons.I18n.NoEntries), window).Show()
+		}
+		if err != nil {
+			commons.GetLogger().Printf("%s: %v", source.Name(), err)
+			dialog.NewError(fmt.Errorf("%s: %w", commons.GetTranslation(commons.I18n.EntriesFailed), err), window).Show()
+		}
+	}()
+}
@@ -0,0 +1,90 @@
+package views
+
+import (
+	"fmt"
+	"pick-a-bro/internal/commons"
+	"pick-a-bro/internal/data"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// importParticipants lets the operator pick a CSV, TSV or XLSX file of participants and opens the column mapping dialog.
+func importParticipants(window fyne.Window) {
+	fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		if reader == nil {
+			return
+		}
+		reader.Close()
+
+		table, err := data.ReadParticipantFile(reader.URI().Path())
+		if err != nil {
+			commons.GetLogger().Println(err)
+			dialog.NewError(fmt.Errorf("%s: %w", commons.GetTranslation(commons.I18n.ImportFailed), err), window).Show()
+			return
+		}
+		showImportMappingDialog(window, table)
+	}, window)
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".tsv", ".txt", ".xlsx"}))
+	fileDialog.Resize(fyne.NewSize(commons.WindowWidth-50, commons.WindowHeight-50))
+	fileDialog.Show()
+}
+
+// showImportMappingDialog shows a select for every participant field to pick the column it is read from,
+// preset with the columns guessed from the header. A detected Patreon dashboard export is mentioned, as only its eligible members are imported.
+// Confirming the dialog imports the participants, loads them and opens the rules view with a summary of the import.
+func showImportMappingDialog(window fyne.Window, table *data.ImportTable) {
+	mapping := data.DetectImportMapping(table.Header)
+
+	options := append([]string{commons.GetTranslation(commons.I18n.ImportNotMapped)}, table.Header...)
+	createColumnSelect := func(column *int) *widget.Select {
+		selectWidget := widget.NewSelect(options, nil)
+		selectWidget.SetSelectedIndex(*column + 1)
+		selectWidget.OnChanged = func(string) {
+			*column = selectWidget.SelectedIndex() - 1
+		}
+		return selectWidget
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem(commons.GetTranslation(commons.I18n.ImportName), createColumnSelect(&mapping.Name)),
+		widget.NewFormItem(commons.GetTranslation(commons.I18n.ImportTier), createColumnSelect(&mapping.Tier)),
+		widget.NewFormItem(commons.GetTranslation(commons.I18n.ImportEntries), createColumnSelect(&mapping.Entries)),
+		widget.NewFormItem(commons.GetTranslation(commons.I18n.ImportID), createColumnSelect(&mapping.ID)),
+		widget.NewFormItem(commons.GetTranslation(commons.I18n.ImportEmail), createColumnSelect(&mapping.Email)),
+	)
+
+	content := container.NewVBox(widget.NewLabel(fmt.Sprintf(commons.GetTranslation(commons.I18n.ImportRows), table.FileName, len(table.Rows))), form)
+	if mapping.PatreonExport {
+		exportLabel := widget.NewLabelWithStyle(commons.GetTranslation(commons.I18n.ImportPatreonExport), fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+		exportLabel.Wrapping = fyne.TextWrapWord
+		content.Add(exportLabel)
+	}
+
+	mappingDialog := dialog.NewCustomConfirm(commons.GetTranslation(commons.I18n.ImportParticipants), commons.GetTranslation(commons.I18n.Import),
+		commons.GetTranslation(commons.I18n.Cancel), content, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			result, err := data.ImportParticipantsToLocalStorage(table, mapping)
+			if err != nil {
+				commons.GetLogger().Println(err)
+				dialog.NewError(fmt.Errorf("%s: %w", commons.GetTranslation(commons.I18n.ImportFailed), err), window).Show()
+				return
+			}
+
+			data.ExtractDataFromFile()
+			SetRules(window)
+			dialog.NewCustom(commons.GetTranslation(commons.I18n.Success), commons.GetTranslation(commons.I18n.Close),
+				createFetchSummary(fmt.Sprintf(commons.GetTranslation(commons.I18n.ImportSummary), len(result.Members)), result), window).Show()
+		}, window)
+	mappingDialog.Resize(fyne.NewSize(500, 400))
+	mappingDialog.Show()
+}
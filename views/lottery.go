@@ -15,6 +15,7 @@ import (
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 
 	"github.com/faiface/beep"
@@ -22,6 +23,9 @@ import (
 	"github.com/faiface/beep/speaker"
 )
 
+// winnerColor is the color of the border of a winner's rectangle and of the podium.
+var winnerColor = color.RGBA{R: 255, G: 190, B: 0, A: 255}
+
 // SetLottery initializes the lottery view and sets up the audio player.
 func SetLottery(window fyne.Window) {
 	const sampleRate = 44100
@@ -30,39 +34,47 @@ func SetLottery(window fyne.Window) {
 }
 
 // lotteryView is a function that creates and displays the lottery view in the application window.
-// It takes a fyne.Window as a parameter and initializes the members list for the lottery.
-// It creates rectangles for each Patreon member in the members list and adds them to the view.
-// It also adds an overlay image to the view.
-// The function then sets the content of the window to the created view and starts the lottery process in a separate goroutine.
+// It takes a fyne.Window as a parameter and makes the draw first, see lottery.Draw, so the board only shows its outcome.
+// It creates a rectangle for each person of the pool, showing their chance to win, and lays the overlay image on a layer of its own above them.
+// The function then sets the content of the window to the created view and starts the animation in a separate goroutine.
+// If no participant is left to draw, the draw is aborted: an information dialog is shown and the main menu is opened again.
 func lotteryView(window fyne.Window) {
-	membersList, _ := lottery.InitMembersList()
+	result, err := lottery.Draw()
+	if err != nil {
+		commons.GetLogger().Println(err)
+		data.ClearDrawRemovals()
+		MainMenu(window)
+		dialog.NewInformation(commons.GetTranslation(commons.I18n.MissingData), commons.GetTranslation(commons.I18n.NoParticipantsLeft), window).Show()
+		return
+	}
 
 	var rectangles []fyne.CanvasObject
 
-	for i := 0; i < len(membersList.PatreonMembers); i++ {
-		rect := createRectangle(membersList.ColorCode, membersList.PatreonMembers[i])
-		rectangles = append(rectangles, rect)
+	for i, member := range result.Pool {
+		rectangles = append(rectangles, createRectangle(result.ColorCode, member, result.Probabilities[i]))
 	}
 
 	overlay := canvas.NewImageFromResource(commons.EmbedImage(
 		commons.GetAsset(commons.AssetsPaths.ImagesPath, commons.AssetsKeys.DrawOverlayImg), commons.AssetsKeys.DrawOverlayImg))
 	overlay.FillMode = canvas.ImageFillStretch
-	rectangles = append(rectangles, overlay)
+	overlay.Hide()
 
 	columns := int(commons.WindowWidth) / 100
-	content := container.NewVScroll(container.NewGridWithColumns(columns, rectangles...))
+	board := container.NewStack(container.NewGridWithColumns(columns, rectangles...), container.NewWithoutLayout(overlay))
+	content := container.NewVScroll(board)
 
-	go runLottery(rectangles, overlay, membersList.PatreonMembers, window, content)
+	go runLottery(rectangles, overlay, result, window, content)
 
 	window.SetContent(content)
 }
 
-// createRectangle creates a rectangle with the specified color based on the member's tier and adds a label with the member's full name.
+// createRectangle creates a rectangle with the specified color based on the member's tier and adds a label with the member's full name
+// and their chance to win.
 // It returns a fyne.CanvasObject that contains the rectangle and label.
-func createRectangle(colors map[string]color.Color, member data.PatreonMember) fyne.CanvasObject {
-	rect := canvas.NewRectangle(colors[member.Tier])
+func createRectangle(colors map[string]color.Color, member data.PatreonMember, probability float64) fyne.CanvasObject {
+	rect := canvas.NewRectangle(colors[member.TierID])
 	rect.SetMinSize(fyne.NewSize(50, 20))
-	text := widget.NewLabel(member.FullName)
+	text := widget.NewLabel(fmt.Sprintf("%s\n%s", member.FullName, formatProbability(probability)))
 	text.Alignment = fyne.TextAlignCenter
 	text.Wrapping = fyne.TextWrapWord
 
@@ -71,16 +83,32 @@ func createRectangle(colors map[string]color.Color, member data.PatreonMember) f
 	return rectContainer
 }
 
-// runLottery runs the lottery process by animating the countdown, selecting random rectangles,
-// playing a beep sound, and displaying the winner dialog.
+// Steps of the animation revealing the first winner and each of the following ones
+const (
+	firstRevealSteps = 40
+	nextRevealSteps  = 20
+)
+
+// formatProbability returns the probability as a percentage, with more decimals below 1%.
+func formatProbability(probability float64) string {
+	if probability >= 0.01 {
+		return fmt.Sprintf("%.1f%%", probability*100)
+	}
+	return fmt.Sprintf("%.2f%%", probability*100)
+}
+
+// runLottery runs the animation of the draw: the countdown, then for every winner the overlay moving over the rectangles
+// along the path of the draw result, with a beep on every step and slowing down towards the end, see lottery.DrawResult.Path.
+// Every path ends on the rectangle of a winner, which is marked with their place before the next winner is revealed.
+// Once every winner is revealed, the winners dialog is shown.
 // It takes the following parameters:
-// - rectangles: a slice of fyne.CanvasObject representing the rectangles to select from.
+// - rectangles: a slice of fyne.CanvasObject representing the rectangles of the people of the pool, in pool order.
 // - overlay: a pointer to a canvas.Image representing the overlay image.
-// - membersList: a slice of data.PatreonMember representing the list of members.
+// - result: the draw result holding the pool and the winners.
 // - window: a fyne.Window representing the application window.
 // - content: a pointer to a container.Scroll representing the scrollable content.
 // The function does not return any value.
-func runLottery(rectangles []fyne.CanvasObject, overlay *canvas.Image, membersList []data.PatreonMember, window fyne.Window, content *container.Scroll) {
+func runLottery(rectangles []fyne.CanvasObject, overlay *canvas.Image, result *lottery.DrawResult, window fyne.Window, content *container.Scroll) {
 	buffer1, _, err := loadMP3ToBuffer(commons.GetAsset(commons.AssetsPaths.AudioPath, commons.AssetsKeys.BeepAudio))
 	if err != nil {
 		fmt.Println(err)
@@ -96,50 +124,73 @@ func runLottery(rectangles []fyne.CanvasObject, overlay *canvas.Image, membersLi
 	go animateImage(&wg, animatedImage)
 	wg.Wait()
 	countdown.Hide()
+	overlay.Show()
 
-	var randomNumber int
-
-	for i := 0; i < 40; i++ {
-		randomNumber = rand.Intn(len(rectangles))
-		if rectangles[randomNumber].Position().Y > content.Offset.Y+600 || rectangles[randomNumber].Position().Y < content.Offset.Y {
-			content.Offset = fyne.NewPos(rectangles[randomNumber].Position().X, rectangles[randomNumber].Position().Y-float32(rand.Intn(300)))
-			content.Refresh()
+	for place := range result.Winners {
+		steps := firstRevealSteps
+		if place > 0 {
+			steps = nextRevealSteps
 		}
-		overlay.Resize(fyne.NewSize(rectangles[randomNumber].Size().Width, rectangles[randomNumber].Size().Height))
-		overlay.Move(fyne.NewPos(rectangles[randomNumber].Position().X, rectangles[randomNumber].Position().Y))
-		beepStream := buffer1.Streamer(0, buffer1.Len())
-		done := make(chan bool)
-		speaker.Play(beep.Seq(beepStream, beep.Callback(func() {
-			done <- true
-		})))
-		<-done
-		content.Refresh()
-		switch {
-		case i >= 20 && i < 30:
-			time.Sleep(time.Millisecond * 200)
-		case i >= 30 && i < 35:
-			time.Sleep(time.Millisecond * 400)
-		case i >= 35 && i < 37:
-			time.Sleep(time.Millisecond * 600)
-		case i >= 37 && i < 38:
-			time.Sleep(time.Millisecond * 800)
-		case i >= 38:
-			time.Sleep(time.Millisecond * 900)
-		default:
-			time.Sleep(time.Millisecond * 100)
+		path := result.Path(place, steps)
+		for i, index := range path {
+			tile := rectangles[index]
+			if tile.Position().Y > content.Offset.Y+600 || tile.Position().Y < content.Offset.Y {
+				content.Offset = fyne.NewPos(tile.Position().X, tile.Position().Y-float32(rand.Intn(300)))
+				content.Refresh()
+			}
+			overlay.Resize(tile.Size())
+			overlay.Move(tile.Position())
+			beepStream := buffer1.Streamer(0, buffer1.Len())
+			done := make(chan bool)
+			speaker.Play(beep.Seq(beepStream, beep.Callback(func() {
+				done <- true
+			})))
+			<-done
+			content.Refresh()
+			time.Sleep(stepDelay(len(path) - 1 - i))
 		}
+		markWinner(rectangles[result.WinnerIndices[place]], place)
+	}
+
+	showWinnerDialog(result.Winners, window)
+}
+
+// stepDelay returns the time the overlay stays on a rectangle, longer as the number of steps left before the winner gets smaller.
+func stepDelay(stepsLeft int) time.Duration {
+	switch {
+	case stepsLeft <= 1:
+		return time.Millisecond * 900
+	case stepsLeft == 2:
+		return time.Millisecond * 800
+	case stepsLeft <= 4:
+		return time.Millisecond * 600
+	case stepsLeft <= 9:
+		return time.Millisecond * 400
+	case stepsLeft <= 19:
+		return time.Millisecond * 200
+	default:
+		return time.Millisecond * 100
 	}
+}
 
-	showWinnerDialog(membersList[randomNumber].FullName, window)
+// markWinner marks the rectangle of a winner, created by createRectangle, with a border and their place before their name.
+func markWinner(tile fyne.CanvasObject, place int) {
+	objects := tile.(*fyne.Container).Objects
+	rect, label := objects[0].(*canvas.Rectangle), objects[1].(*widget.Label)
+	rect.StrokeColor = winnerColor
+	rect.StrokeWidth = 4
+	rect.Refresh()
+	label.SetText(fmt.Sprintf(commons.GetTranslation(commons.I18n.WinnerPlace), place+1, label.Text))
 }
 
-// showWinnerDialog displays a dialog box to congratulate the winner and play a winner audio.
-// It takes the winner's name and a fyne.Window as parameters.
-// The function loads an MP3 audio file, plays the audio, and creates a dialog box with a congratulatory message.
+// showWinnerDialog displays a dialog box to congratulate the winners and play a winner audio.
+// It takes the winning members, in the order they were drawn, and a fyne.Window as parameters.
+// The function loads an MP3 audio file, plays the audio, and creates a dialog box with a congratulatory message
+// for a single winner, or a podium listing the winners by place.
 // The dialog box is then shown to the user.
-// After the dialog box is closed, the function adds the winner's name to the winners list (if not in test mode)
-// and returns to the main menu.
-func showWinnerDialog(winnerName string, window fyne.Window) {
+// After the dialog box is closed, the function adds the draw to the winners list (if not in test mode),
+// brings back the members removed for this draw and returns to the main menu.
+func showWinnerDialog(winners []data.PatreonMember, window fyne.Window) {
 	buffer, _, err := loadMP3ToBuffer(commons.GetAsset(commons.AssetsPaths.AudioPath, commons.AssetsKeys.WinnerAudio))
 	if err != nil {
 		commons.GetLogger().Fatal(err)
@@ -147,20 +198,48 @@ func showWinnerDialog(winnerName string, window fyne.Window) {
 
 	winnerStream := buffer.Streamer(0, buffer.Len())
 	speaker.Play(winnerStream)
-	congratsLabel := widget.NewLabel(fmt.Sprintf(commons.GetTranslation(commons.I18n.Congrats), winnerName))
-	dialogContent := container.NewHBox(congratsLabel)
+	var dialogContent fyne.CanvasObject = container.NewHBox(widget.NewLabel(fmt.Sprintf(commons.GetTranslation(commons.I18n.Congrats), winners[0].FullName)))
+	if len(winners) > 1 {
+		dialogContent = createPodium(winners)
+	}
 
 	winnersDialog := dialog.NewCustom(commons.GetTranslation(commons.I18n.Winner), "Done", dialogContent, window)
 	winnersDialog.Resize(fyne.NewSize(200, 200))
 	winnersDialog.Show()
 	winnersDialog.SetOnClosed(func() {
 		if !commons.GetPreferences().Bool(commons.Settings.TestMode) {
-			lottery.AddToWinnersList(winnerName)
+			lottery.AddToWinnersList(winners)
 		}
+		data.ClearDrawRemovals()
 		MainMenu(window)
 	})
 }
 
+// createPodium creates the summary of a draw with several winners: the first three on a podium, the first in the middle
+// and higher than the second and the third, and the following winners listed by place below it.
+func createPodium(winners []data.PatreonMember) fyne.CanvasObject {
+	podiumHeights := []float32{120, 90, 60}
+	steps := make([]fyne.CanvasObject, 0, 3)
+	for place := 0; place < len(winners) && place < len(podiumHeights); place++ {
+		block := canvas.NewRectangle(winnerColor)
+		block.SetMinSize(fyne.NewSize(120, podiumHeights[place]))
+		name := widget.NewLabelWithStyle(winners[place].FullName, fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+		name.Wrapping = fyne.TextWrapWord
+		number := widget.NewLabelWithStyle(fmt.Sprint(place+1), fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+		steps = append(steps, container.NewVBox(layout.NewSpacer(), name, container.NewStack(block, number)))
+	}
+	// The second goes left of the first and the third right of it
+	if len(steps) > 1 {
+		steps[0], steps[1] = steps[1], steps[0]
+	}
+
+	podium := container.NewVBox(container.NewHBox(layout.NewSpacer(), container.NewHBox(steps...), layout.NewSpacer()))
+	for place := len(podiumHeights); place < len(winners); place++ {
+		podium.Add(widget.NewLabel(fmt.Sprintf(commons.GetTranslation(commons.I18n.WinnerPlace), place+1, winners[place].FullName)))
+	}
+	return podium
+}
+
 // loadMP3ToBuffer loads an MP3 file from the specified filePath and returns a buffer, format, and error.
 func loadMP3ToBuffer(filePath string) (*beep.Buffer, beep.Format, error) {
 	audioFS, err := commons.GetAudioFS().Open(filePath)
@@ -1,10 +1,13 @@
 package views
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"pick-a-bro/internal/commons"
 	"pick-a-bro/internal/data"
 	"pick-a-bro/internal/lottery"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -15,11 +18,14 @@ import (
 
 // MainMenu is a function that creates and displays the main menu of the application.
 // It takes a `window` parameter of type `fyne.Window` to display the menu.
-// The main menu consists of several buttons, including a "New Draw" button, a "Settings" button,
+// The main menu consists of several buttons, including a "New Draw" button, an "Import participants" button, a "Settings" button,
 // a "Previous Winners" button, and a "Test Mode" checkbox.
 // Clicking the "New Draw" button will either handle the test mode or the normal mode based on the user's preferences.
+// Clicking the "Import participants" button will read the participants from a CSV, TSV or XLSX file.
+// The participant sources the draw uses are picked with a checkbox each, see createSourcesSelector.
 // Clicking the "Settings" button will open the preferences panel.
-// Clicking the "Previous Winners" button will display a list of previous winners and provide an option to clear the winners list.
+// Clicking the "Previous Winners" button will display the previous draws with their winners, marking the ones added or edited by hand,
+// and provide an option to clear the winners list.
 // Clicking the "Test Mode" checkbox will toggle the test mode on or off based on the user's selection.
 // The main menu is displayed within the specified `window`.
 func MainMenu(window fyne.Window) {
@@ -31,6 +37,10 @@ func MainMenu(window fyne.Window) {
 		}
 	})
 
+	importButton := widget.NewButton(commons.GetTranslation(commons.I18n.ImportParticipants), func() {
+		importParticipants(window)
+	})
+
 	settingsButton := widget.NewButton(commons.GetTranslation(commons.I18n.Settings), func() {
 		preferencesPanel(window)
 	})
@@ -38,8 +48,18 @@ func MainMenu(window fyne.Window) {
 	previousWinnersButton := widget.NewButton(commons.GetTranslation(commons.I18n.PreviousWinners), func() {
 		winners := []fyne.CanvasObject{}
 
-		for _, d := range lottery.GetWinnersList() {
-			winners = append(winners, widget.NewLabel(d.FullName), widget.NewLabel(d.DateTime))
+		for _, d := range lottery.GetDraws() {
+			names := make([]string, 0, len(d.Winners))
+			for _, winner := range d.Winners {
+				name := winner.FullName
+				if label, found := overrideLabels[winner.Override]; found {
+					name = fmt.Sprintf("%s (%s)", winner.FullName, commons.GetTranslation(label))
+				}
+				names = append(names, name)
+			}
+			namesLabel := widget.NewLabel(strings.Join(names, ", "))
+			namesLabel.Wrapping = fyne.TextWrapWord
+			winners = append(winners, namesLabel, widget.NewLabel(d.DateTime))
 		}
 		grid := container.NewGridWithColumns(2, winners...)
 		scroll := container.NewVScroll(grid)
@@ -73,7 +93,8 @@ func MainMenu(window fyne.Window) {
 
 	testModeCheckbox.SetChecked(commons.GetPreferences().BoolWithFallback(commons.Settings.TestMode, false))
 
-	mainButtons := container.NewVBox(layout.NewSpacer(), newDrawButton, settingsButton, previousWinnersButton, testModeCheckbox)
+	mainButtons := container.NewVBox(layout.NewSpacer(), newDrawButton, importButton, settingsButton, previousWinnersButton,
+		createSourcesSelector(window), testModeCheckbox)
 	content := container.New(layout.NewStackLayout(), commons.GetBackgroundImage(), mainButtons)
 	window.SetContent(content)
 }
@@ -105,15 +126,25 @@ func handleTestMode(window fyne.Window) {
 	dialogPanel.Show()
 }
 
+// handleNormalMode loads the participants of the selected sources. If none has a stored list, the operator is sent to the preferences to fetch the patreons
+// when Patreon is selected, or else the selected sources are refreshed.
+// If a selected source can be refreshed, the operator is asked whether to refresh the list first, otherwise the rules view is opened.
 func handleNormalMode(window fyne.Window) {
 	if !data.ExtractDataFromFile() {
+		if !isSelected(data.PatreonSourceID) && canRefresh(data.DrawSources()) {
+			fetchPatreonsList(window)
+			return
+		}
 		dialog.NewInformation(commons.GetTranslation(commons.I18n.MissingData), commons.GetTranslation(commons.I18n.NoPatreons), window).Show()
 		preferencesPanel(window)
+	} else if !canRefresh(data.DrawSources()) {
+		SetRules(window)
 	} else {
 		dialog.NewCustomConfirm(commons.GetTranslation(commons.I18n.PatreonsList), commons.GetTranslation(commons.I18n.Yes),
 			commons.GetTranslation(commons.I18n.No), widget.NewLabel(commons.GetTranslation(commons.I18n.RefreshPatreonsList)), func(resp bool) {
 				if resp {
 					fetchPatreonsList(window)
+					return
 				}
 				SetRules(window)
 			}, window).Show()
@@ -124,20 +155,215 @@ func checkAndGenerateTestData(window fyne.Window) {
 	if !data.ExtractDataFromFile() {
 		dialog.NewCustomWithoutButtons(commons.GetTranslation(commons.I18n.TestData),
 			widget.NewLabel(commons.GetTranslation(commons.I18n.TestDataGenerated)), window).Show()
-		members, tiers := data.FetchMembersToLocalStorage()
-		data.SetMembersList(members)
-		data.SetTiersMap(tiers)
+		if _, err := data.RefreshSources(context.Background(), data.DrawSources(), nil); err != nil {
+			return
+		}
+		data.ExtractDataFromFile()
+	}
+}
+
+// isSelected reports whether the draw uses the source with the given ID.
+func isSelected(id string) bool {
+	for _, selected := range data.GetSelectedSourceIDs() {
+		if selected == id {
+			return true
+		}
+	}
+	return false
+}
+
+// canRefresh reports whether any of the sources can fetch a new list.
+func canRefresh(sources []data.ParticipantSource) bool {
+	for _, source := range sources {
+		if source.Capabilities().Refresh {
+			return true
+		}
 	}
+	return false
 }
 
+// fetchPatreonsList refreshes the lists of the sources the draw uses and opens the rules view once the fetch is over.
+// If some patreons were skipped or support several campaigns, the roster changed since the previous fetch or an interrupted fetch was continued,
+// a summary dialog is shown on top of the rules view.
+// If the fetch fails, an error dialog is shown and the rules view uses the previously stored list.
 func fetchPatreonsList(window fyne.Window) {
-	watingDialog := dialog.NewCustomWithoutButtons(commons.GetTranslation(commons.I18n.FetchingPatreons),
-		widget.NewLabel(fmt.Sprintf("%s...", commons.GetTranslation(commons.I18n.FetchingPatreons))), window)
-	watingDialog.Show()
-	if members, _ := data.FetchMembersToLocalStorage(); members == nil {
-		watingDialog.Hide()
-		dialog.NewError(fmt.Errorf(commons.GetTranslation(commons.I18n.ErrorFetchingPatreons)), window).Show()
+	fetchMembers(window, data.DrawSources(), func(result *data.FetchResult, err error) {
+		if err != nil {
+			data.ExtractDataFromFile()
+			SetRules(window)
+			showFetchError(window, err)
+			return
+		}
+
+		data.ExtractDataFromFile()
+		SetRules(window)
+		if hasFetchDetails(result) || result.Shared > 0 || result.ResumedFromPage > 0 {
+			dialog.NewCustom(commons.GetTranslation(commons.I18n.Success), commons.GetTranslation(commons.I18n.Close),
+				createFetchSummary(fmt.Sprintf(commons.GetTranslation(commons.I18n.FetchSummary), len(result.Members)), result), window).Show()
+		}
+	})
+}
+
+// fetchMembers refreshes the given sources in the background while a waiting dialog is shown, see data.RefreshSources.
+// The dialog shows the current step of the fetch and, while fetching, the pages and patreons received so far,
+// with a progress bar filling up page by page once the number of pages is known, or else an infinite one.
+// Closing the dialog with its cancel button cancels the fetch, including a pending browser authorization.
+// When the fetch is over the dialog is hidden and onDone is called with the result or the error.
+func fetchMembers(window fyne.Window, refreshed []data.ParticipantSource, onDone func(result *data.FetchResult, err error)) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	progressLabel := widget.NewLabel(fmt.Sprintf("%s...", commons.GetTranslation(commons.I18n.FetchingPatreons)))
+	progressBar := widget.NewProgressBar()
+	progressBar.Hide()
+	infiniteBar := widget.NewProgressBarInfinite()
+	waitingDialog := dialog.NewCustom(commons.GetTranslation(commons.I18n.FetchingPatreons), commons.GetTranslation(commons.I18n.Cancel),
+		container.NewVBox(infiniteBar, progressBar, progressLabel), window)
+	waitingDialog.Resize(fyne.NewSize(400, 150))
+	waitingDialog.SetOnClosed(cancel)
+	waitingDialog.Show()
+
+	go func() {
+		result, err := data.RefreshSources(ctx, refreshed, func(event data.FetchEvent) {
+			progressLabel.SetText(describeFetchEvent(event))
+			if event.Step == data.FetchStepFetch && event.TotalPages > 0 {
+				progressBar.Max = float64(event.TotalPages)
+				progressBar.SetValue(float64(min(event.Page, event.TotalPages)))
+				infiniteBar.Hide()
+				progressBar.Show()
+			} else {
+				progressBar.Hide()
+				infiniteBar.Show()
+			}
+		})
+		waitingDialog.Hide()
+		onDone(result, err)
+	}()
+}
+
+// describeFetchEvent returns the translated text describing the progress of a fetch, preceded by the campaign it concerns.
+func describeFetchEvent(event data.FetchEvent) string {
+	var description string
+	switch event.Step {
+	case data.FetchStepAuth:
+		description = commons.GetTranslation(commons.I18n.FetchStepAuth)
+	case data.FetchStepWrite:
+		description = fmt.Sprintf(commons.GetTranslation(commons.I18n.FetchStepWrite), event.Members)
+	default:
+		if event.TotalPages > 0 {
+			description = fmt.Sprintf(commons.GetTranslation(commons.I18n.FetchStepFetchTotal), event.Page, event.TotalPages, event.Members)
+		} else {
+			description = fmt.Sprintf(commons.GetTranslation(commons.I18n.FetchStepFetch), event.Page, event.Members)
+		}
+	}
+	if event.Campaign == "" {
+		return description
+	}
+	return fmt.Sprintf("%s: %s", event.Campaign, description)
+}
+
+// createFetchSummary creates the content summarizing a fetch or an import: the headline with the number of patreons received, the pages received,
+// the patreons supporting several campaigns, the page an interrupted fetch was continued from and a scrollable list of details: the skipped patreons
+// with the reason each one was skipped, and the changes since the previous fetch. An import has no pages, so they are left out.
+func createFetchSummary(headline string, result *data.FetchResult) fyne.CanvasObject {
+	summary := container.NewVBox(widget.NewLabel(headline))
+	if result.Pages > 0 {
+		summary.Add(widget.NewLabel(fmt.Sprintf(commons.GetTranslation(commons.I18n.FetchPages), result.Pages)))
+	}
+	if result.Shared > 0 {
+		summary.Add(widget.NewLabel(fmt.Sprintf(commons.GetTranslation(commons.I18n.FetchSharedPatreons), result.Shared)))
+	}
+	if result.ResumedFromPage > 0 {
+		summary.Add(widget.NewLabel(fmt.Sprintf(commons.GetTranslation(commons.I18n.FetchResumed), result.ResumedFromPage)))
+	}
+	if !hasFetchDetails(result) {
+		return summary
+	}
+
+	details := container.NewVBox()
+	if len(result.Skipped) > 0 {
+		skipped := make([]string, 0, len(result.Skipped))
+		for _, member := range result.Skipped {
+			skipped = append(skipped, fmt.Sprintf("%s: %s", member.FullName, member.Reason))
+		}
+		addSummarySection(details, commons.GetTranslation(commons.I18n.SkippedMembers), skipped)
+	}
+	if result.Diff != nil && !result.Diff.IsEmpty() {
+		addRosterDiff(details, result.Diff)
+	}
+
+	scroll := container.NewVScroll(details)
+	scroll.SetMinSize(fyne.NewSize(400, 250))
+	summary.Add(scroll)
+	return summary
+}
+
+// hasFetchDetails reports whether the fetch has skipped patreons or changes since the previous fetch to list.
+func hasFetchDetails(result *data.FetchResult) bool {
+	return len(result.Skipped) > 0 || (result.Diff != nil && !result.Diff.IsEmpty())
+}
+
+// addRosterDiff adds a section for every kind of change since the previous fetch that has entries.
+func addRosterDiff(details *fyne.Container, diff *data.RosterDiff) {
+	details.Add(widget.NewLabelWithStyle(commons.GetTranslation(commons.I18n.RosterChanges), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+
+	joined := make([]string, 0, len(diff.Joined))
+	for _, member := range diff.Joined {
+		joined = append(joined, fmt.Sprintf("%s (%s)", member.FullName, member.Tier))
+	}
+	left := make([]string, 0, len(diff.Left))
+	for _, member := range diff.Left {
+		left = append(left, fmt.Sprintf("%s (%s)", member.FullName, member.Tier))
+	}
+	upgraded := make([]string, 0, len(diff.Upgraded))
+	for _, change := range diff.Upgraded {
+		upgraded = append(upgraded, fmt.Sprintf("%s: %s → %s", change.FullName, change.From, change.To))
+	}
+	downgraded := make([]string, 0, len(diff.Downgraded))
+	for _, change := range diff.Downgraded {
+		downgraded = append(downgraded, fmt.Sprintf("%s: %s → %s", change.FullName, change.From, change.To))
+	}
+	tierChanged := make([]string, 0, len(diff.TierChanged))
+	for _, change := range diff.TierChanged {
+		tierChanged = append(tierChanged, fmt.Sprintf("%s: %s → %s", change.FullName, change.From, change.To))
+	}
+	statusChanged := make([]string, 0, len(diff.StatusChanged))
+	for _, change := range diff.StatusChanged {
+		statusChanged = append(statusChanged, fmt.Sprintf("%s: %s/%s → %s/%s", change.FullName,
+			change.FromPatronStatus, change.FromLastChargeStatus, change.ToPatronStatus, change.ToLastChargeStatus))
+	}
+
+	addSummarySection(details, fmt.Sprintf(commons.GetTranslation(commons.I18n.RosterJoined), len(joined)), joined)
+	addSummarySection(details, fmt.Sprintf(commons.GetTranslation(commons.I18n.RosterLeft), len(left)), left)
+	addSummarySection(details, fmt.Sprintf(commons.GetTranslation(commons.I18n.RosterUpgraded), len(upgraded)), upgraded)
+	addSummarySection(details, fmt.Sprintf(commons.GetTranslation(commons.I18n.RosterDowngraded), len(downgraded)), downgraded)
+	addSummarySection(details, fmt.Sprintf(commons.GetTranslation(commons.I18n.RosterTierChanged), len(tierChanged)), tierChanged)
+	addSummarySection(details, fmt.Sprintf(commons.GetTranslation(commons.I18n.RosterStatusChanged), len(statusChanged)), statusChanged)
+}
+
+// addSummarySection adds a titled list of lines to the details of a fetch summary. Empty lists are left out.
+func addSummarySection(details *fyne.Container, title string, lines []string) {
+	if len(lines) == 0 {
 		return
 	}
-	watingDialog.Hide()
+	details.Add(widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+	for _, line := range lines {
+		details.Add(widget.NewLabel(line))
+	}
+}
+
+// showFetchError shows an error dialog explaining why the patreons could not be fetched.
+// For a fetch interrupted after some pages, it also tells how far it got and that the next fetch continues from there.
+// A fetch cancelled by the operator only gets an information dialog.
+func showFetchError(window fyne.Window, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, data.ErrAuthCancelled) {
+		dialog.NewInformation(commons.GetTranslation(commons.I18n.FetchingPatreons), commons.GetTranslation(commons.I18n.FetchCancelled), window).Show()
+		return
+	}
+
+	var interrupted *data.FetchInterruptedError
+	if errors.As(err, &interrupted) {
+		err = fmt.Errorf("%s: %s: %w", interrupted.Campaign,
+			fmt.Sprintf(commons.GetTranslation(commons.I18n.FetchInterrupted), interrupted.Pages, interrupted.Members), interrupted.Err)
+	}
+	dialog.NewError(fmt.Errorf("%s: %w", commons.GetTranslation(commons.I18n.ErrorFetchingPatreons), err), window).Show()
 }
@@ -1,10 +1,12 @@
 package views
 
 import (
+	"context"
 	"fmt"
 	"image/color"
 	"pick-a-bro/internal/commons"
 	"pick-a-bro/internal/data"
+	"strconv"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -12,12 +14,16 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
+	"github.com/skip2/go-qrcode"
 )
 
+// editedProfile is the campaign profile whose credentials the preferences panel shows.
+var editedProfile data.Profile
+
 // preferencesPanel is a function that creates and displays the preferences panel in the application window.
 // It takes a fyne.Window as a parameter and sets the content of the window to the preferences panel.
 func preferencesPanel(window fyne.Window) {
-	formItems := createFormItems()
+	formItems := createFormItems(window)
 	preferencesProcessing(formItems, "onLoad")
 
 	form := createForm(window, formItems)
@@ -25,28 +31,287 @@ func preferencesPanel(window fyne.Window) {
 	discardPreferences := createDiscardPreferencesButton(window)
 	readLogs := createReadLogsButton(window)
 
-	mainButtons := container.NewVBox(layout.NewSpacer(), discardPreferences, readLogs)
+	mainButtons := container.NewVBox(discardPreferences, readLogs)
 	background := createBackground()
-	rulesView := container.NewStack(background, form)
+	rulesView := container.NewStack(background, container.NewVScroll(form))
 
-	content := container.New(layout.NewStackLayout(), commons.GetBackgroundImage(), rulesView, mainButtons)
+	content := container.New(layout.NewStackLayout(), commons.GetBackgroundImage(), container.NewBorder(nil, mainButtons, nil, nil, rulesView))
 	window.SetContent(content)
 }
 
 // createFormItems creates and returns a slice of *widget.FormItem.
-// Each *widget.FormItem consists of a label and a password entry widget.
+// Each *widget.FormItem consists of a label and an entry widget.
 // The labels are predefined constants from the commons package.
-// The password entry widgets are created using widget.NewPasswordEntry().
-func createFormItems() []*widget.FormItem {
+// The credentials use password entries created with widget.NewPasswordEntry(),
+// while the redirect URI uses a plain entry showing the default URI as placeholder.
+// The first item picks the campaign profile the credentials, the campaign and the authorization items belong to.
+// The last items select the authorization mode and open the manual login, which saves the form first.
+// The data directory entry shows the directory in use as placeholder.
+func createFormItems(window fyne.Window) []*widget.FormItem {
+	var formItems []*widget.FormItem
+	profileForm := widget.NewFormItem(commons.GetTranslation(commons.I18n.Campaign), createProfileRow(window, func() {
+		preferencesProcessing(formItems, "onSave")
+	}))
+
+	campaignNameEntry := widget.NewEntry()
+	if editedProfile.ID == "" {
+		campaignNameEntry.SetPlaceHolder(commons.GetTranslation(commons.I18n.MainCampaign))
+	}
 	clientIdEntry := widget.NewPasswordEntry()
 	clientSecretEntry := widget.NewPasswordEntry()
 	campaignIdEntry := widget.NewPasswordEntry()
+	redirectURIEntry := widget.NewEntry()
+	redirectURIEntry.SetPlaceHolder(commons.RedirectURI)
+	creatorAccessTokenEntry := widget.NewPasswordEntry()
+	creatorRefreshTokenEntry := widget.NewPasswordEntry()
+	noTierNameEntry := widget.NewEntry()
+	noTierNameEntry.SetPlaceHolder(commons.DefaultNoTierName)
+	dataDirEntry := widget.NewEntry()
+	dataDirEntry.SetPlaceHolder(commons.GetDataDir())
 
+	campaignNameForm := widget.NewFormItem(commons.CampaignName, campaignNameEntry)
 	clientIdForm := widget.NewFormItem(commons.ClientId, clientIdEntry)
 	clientSecretForm := widget.NewFormItem(commons.ClientSecret, clientSecretEntry)
 	campaignIdForm := widget.NewFormItem(commons.CampaignId, campaignIdEntry)
+	redirectURIForm := widget.NewFormItem(commons.CallbackURI, redirectURIEntry)
+	creatorAccessTokenForm := widget.NewFormItem(commons.CreatorAccessToken, creatorAccessTokenEntry)
+	creatorRefreshTokenForm := widget.NewFormItem(commons.CreatorRefreshToken, creatorRefreshTokenEntry)
+	authModeForm := widget.NewFormItem(commons.GetTranslation(commons.I18n.AuthMode), createAuthModeSelect())
+	// The authorization URL is built from the stored profile, so what was entered is saved first
+	manualLoginForm := widget.NewFormItem("", widget.NewButton(commons.GetTranslation(commons.I18n.ManualLogin), func() {
+		preferencesProcessing(formItems, "onSave")
+		showManualLoginDialog(window)
+	}))
+
+	noTierNameForm := widget.NewFormItem(commons.NoTierName, noTierNameEntry)
+	multiTierPolicyForm := widget.NewFormItem(commons.GetTranslation(commons.I18n.MultiTierPolicy), createMultiTierPolicySelect())
+	pageSizeForm := widget.NewFormItem(commons.GetTranslation(commons.I18n.PageSize), createPageSizeSelect())
+	snapshotRetentionForm := widget.NewFormItem(commons.GetTranslation(commons.I18n.SnapshotRetention), createSnapshotRetentionSelect())
+	dataDirForm := widget.NewFormItem(commons.DataDirectory, dataDirEntry)
+	dataDirForm.HintText = commons.GetTranslation(commons.I18n.DataDirHint)
+
+	eligibilityForm := widget.NewFormItem(commons.GetTranslation(commons.I18n.Eligibility), widget.NewSeparator())
+	patronStatusForm := widget.NewFormItem(commons.GetTranslation(commons.I18n.PatronStatus),
+		createStatusCheckGroup(commons.PatronStatuses, commons.PatronStatusLabels, commons.AllowedPatronStatuses, commons.DefaultPatronStatuses))
+	chargeStatusForm := widget.NewFormItem(commons.GetTranslation(commons.I18n.LastChargeStatus),
+		createStatusCheckGroup(commons.ChargeStatuses, commons.ChargeStatusLabels, commons.AllowedChargeStatuses, commons.DefaultChargeStatuses))
+
+	formItems = []*widget.FormItem{profileForm, campaignNameForm, clientIdForm, clientSecretForm, campaignIdForm, redirectURIForm,
+		authModeForm, manualLoginForm, creatorAccessTokenForm, creatorRefreshTokenForm,
+		noTierNameForm, multiTierPolicyForm, pageSizeForm, snapshotRetentionForm, dataDirForm, eligibilityForm, patronStatusForm, chargeStatusForm}
+	return formItems
+}
+
+// createProfileRow creates a select to pick the campaign profile shown in the panel, with buttons to add a profile,
+// which is then shown, and to remove the shown one. The default profile can not be removed. Before another profile is shown,
+// saveEdits stores what was entered for the shown one, then the panel is redrawn.
+func createProfileRow(window fyne.Window, saveEdits func()) *fyne.Container {
+	profiles := data.GetProfiles()
+	names := make([]string, len(profiles))
+	selected := 0
+	for i, profile := range profiles {
+		names[i] = profile.Name()
+		if profile == editedProfile {
+			selected = i
+		}
+	}
+	editedProfile = profiles[selected]
+
+	selectWidget := widget.NewSelect(names, nil)
+	selectWidget.SetSelectedIndex(selected)
+	selectWidget.OnChanged = func(string) {
+		saveEdits()
+		editedProfile = profiles[selectWidget.SelectedIndex()]
+		preferencesPanel(window)
+	}
+
+	addButton := widget.NewButton(commons.GetTranslation(commons.I18n.AddCampaign), func() {
+		saveEdits()
+		editedProfile = data.AddProfile()
+		preferencesPanel(window)
+	})
+
+	removeButton := widget.NewButton(commons.GetTranslation(commons.I18n.RemoveCampaign), func() {
+		confirmDialog := dialog.NewConfirm(commons.GetTranslation(commons.I18n.RemoveCampaign),
+			fmt.Sprintf(commons.GetTranslation(commons.I18n.ConfirmRemoveCampaign), editedProfile.Name()), func(confirmed bool) {
+				if !confirmed {
+					return
+				}
+				data.RemoveProfile(editedProfile)
+				editedProfile = data.Profile{}
+				preferencesPanel(window)
+			}, window)
+		confirmDialog.SetConfirmText(commons.GetTranslation(commons.I18n.Yes))
+		confirmDialog.SetDismissText(commons.GetTranslation(commons.I18n.No))
+		confirmDialog.Show()
+	})
+	if editedProfile.ID == "" {
+		removeButton.Disable()
+	}
+
+	return container.NewHBox(selectWidget, addButton, removeButton)
+}
 
-	return []*widget.FormItem{clientIdForm, clientSecretForm, campaignIdForm}
+// createStatusCheckGroup creates a check group with the translated labels of the given statuses,
+// checking the ones allowed in the preferences under preferenceKey, or the defaults if nothing is stored.
+// The allowed statuses are stored in the preferences as soon as the selection changes.
+func createStatusCheckGroup(statuses []string, labels map[string]string, preferenceKey string, defaults []string) *widget.CheckGroup {
+	options := make([]string, len(statuses))
+	statusByLabel := make(map[string]string, len(statuses))
+	for i, status := range statuses {
+		options[i] = commons.GetTranslation(labels[status])
+		statusByLabel[options[i]] = status
+	}
+
+	selected := []string{}
+	for _, status := range commons.GetPreferences().StringListWithFallback(preferenceKey, defaults) {
+		selected = append(selected, commons.GetTranslation(labels[status]))
+	}
+
+	checkGroup := widget.NewCheckGroup(options, nil)
+	checkGroup.SetSelected(selected)
+	checkGroup.OnChanged = func(checked []string) {
+		allowed := make([]string, 0, len(checked))
+		for _, label := range checked {
+			allowed = append(allowed, statusByLabel[label])
+		}
+		commons.GetPreferences().SetStringList(preferenceKey, allowed)
+	}
+	return checkGroup
+}
+
+// createMultiTierPolicySelect creates a select to choose whether members with several tiers count
+// only with their highest tier or with every tier. The selected policy is stored in the preferences as soon as it changes.
+func createMultiTierPolicySelect() *widget.Select {
+	policies := []string{commons.MultiTierPolicies.Highest, commons.MultiTierPolicies.All}
+	labels := []string{commons.GetTranslation(commons.I18n.MultiTierHighest), commons.GetTranslation(commons.I18n.MultiTierAll)}
+
+	selectWidget := widget.NewSelect(labels, nil)
+	selected := 0
+	for i, policy := range policies {
+		if policy == commons.GetPreferences().StringWithFallback(commons.MultiTierPolicy, commons.MultiTierPolicies.Highest) {
+			selected = i
+		}
+	}
+	selectWidget.SetSelectedIndex(selected)
+	selectWidget.OnChanged = func(string) {
+		commons.GetPreferences().SetString(commons.MultiTierPolicy, policies[selectWidget.SelectedIndex()])
+	}
+	return selectWidget
+}
+
+// createPageSizeSelect creates a select to choose how many members are requested per page when fetching.
+// Smaller pages mean more requests, but less to download again when a page fails.
+func createPageSizeSelect() *widget.Select {
+	labels := make([]string, len(commons.PageSizes))
+	selected := 0
+	for i, size := range commons.PageSizes {
+		labels[i] = strconv.Itoa(size)
+		if size == commons.GetPreferences().IntWithFallback(commons.PageSize, commons.DefaultPageSize) {
+			selected = i
+		}
+	}
+
+	selectWidget := widget.NewSelect(labels, nil)
+	selectWidget.SetSelectedIndex(selected)
+	selectWidget.OnChanged = func(string) {
+		commons.GetPreferences().SetInt(commons.PageSize, commons.PageSizes[selectWidget.SelectedIndex()])
+	}
+	return selectWidget
+}
+
+// createSnapshotRetentionSelect creates a select to choose how many days the snapshots of the patreons list are kept.
+// Older snapshots are pruned after every fetch, unless they are kept forever.
+func createSnapshotRetentionSelect() *widget.Select {
+	labels := make([]string, len(commons.SnapshotRetentionOptions))
+	selected := 0
+	for i, days := range commons.SnapshotRetentionOptions {
+		labels[i] = fmt.Sprintf(commons.GetTranslation(commons.I18n.RetentionDays), days)
+		if days == 0 {
+			labels[i] = commons.GetTranslation(commons.I18n.RetentionForever)
+		}
+		if days == commons.GetPreferences().IntWithFallback(commons.SnapshotRetentionDays, 0) {
+			selected = i
+		}
+	}
+
+	selectWidget := widget.NewSelect(labels, nil)
+	selectWidget.SetSelectedIndex(selected)
+	selectWidget.OnChanged = func(string) {
+		commons.GetPreferences().SetInt(commons.SnapshotRetentionDays, commons.SnapshotRetentionOptions[selectWidget.SelectedIndex()])
+	}
+	return selectWidget
+}
+
+// createAuthModeSelect creates a select to choose between the browser authorization, the manual authorization
+// and a creator access token for the edited profile. The selected mode is stored in the preferences as soon as it changes.
+func createAuthModeSelect() *widget.Select {
+	profile := editedProfile
+	modes := []string{commons.AuthModes.Browser, commons.AuthModes.Manual, commons.AuthModes.Creator}
+	labels := []string{commons.GetTranslation(commons.I18n.AuthModeBrowser), commons.GetTranslation(commons.I18n.AuthModeManual),
+		commons.GetTranslation(commons.I18n.AuthModeCreator)}
+
+	selectWidget := widget.NewSelect(labels, nil)
+	selected := 0
+	for i, mode := range modes {
+		if mode == commons.GetPreferences().StringWithFallback(profile.Key(commons.AuthMode), commons.AuthModes.Browser) {
+			selected = i
+		}
+	}
+	selectWidget.SetSelectedIndex(selected)
+	selectWidget.OnChanged = func(string) {
+		commons.GetPreferences().SetString(profile.Key(commons.AuthMode), modes[selectWidget.SelectedIndex()])
+	}
+	return selectWidget
+}
+
+// showManualLoginDialog shows the authorization URL of the edited profile as text and as a QR code, together with an entry
+// where the operator pastes the code or the URL the browser was redirected to.
+// Confirming the dialog exchanges the code for a token in the background and reports the outcome.
+func showManualLoginDialog(window fyne.Window) {
+	authorization := data.StartManualAuthorization(editedProfile)
+
+	urlEntry := widget.NewEntry()
+	urlEntry.SetText(authorization.URL)
+	urlEntry.Wrapping = fyne.TextWrapBreak
+	urlEntry.MultiLine = true
+	copyButton := widget.NewButton(commons.GetTranslation(commons.I18n.Copy), func() {
+		window.Clipboard().SetContent(authorization.URL)
+	})
+
+	infoLabel := widget.NewLabel(commons.GetTranslation(commons.I18n.ManualLoginInfo))
+	infoLabel.Wrapping = fyne.TextWrapWord
+
+	content := container.NewVBox(infoLabel, urlEntry, copyButton)
+	if png, err := qrcode.Encode(authorization.URL, qrcode.Medium, 256); err != nil {
+		commons.GetLogger().Println(err)
+	} else {
+		qrImage := canvas.NewImageFromResource(fyne.NewStaticResource("authorization-qr.png", png))
+		qrImage.FillMode = canvas.ImageFillContain
+		qrImage.SetMinSize(fyne.NewSize(200, 200))
+		content.Add(qrImage)
+	}
+
+	codeEntry := widget.NewEntry()
+	codeEntry.SetPlaceHolder(commons.GetTranslation(commons.I18n.AuthCode))
+	content.Add(codeEntry)
+
+	loginDialog := dialog.NewCustomConfirm(commons.GetTranslation(commons.I18n.ManualLogin), commons.GetTranslation(commons.I18n.Login),
+		commons.GetTranslation(commons.I18n.Cancel), container.NewVScroll(content), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			go func() {
+				if err := authorization.Complete(context.Background(), codeEntry.Text); err != nil {
+					commons.GetLogger().Println(err)
+					dialog.NewError(fmt.Errorf("%s: %w", commons.GetTranslation(commons.I18n.AuthFailed), err), window).Show()
+					return
+				}
+				dialog.NewInformation(commons.GetTranslation(commons.I18n.Success), commons.GetTranslation(commons.I18n.AuthSuccess), window).Show()
+			}()
+		}, window)
+	loginDialog.Resize(fyne.NewSize(500, 550))
+	loginDialog.Show()
 }
 
 // createForm creates a new widget.Form with the specified formItems and submit handler.
@@ -62,14 +327,11 @@ func createForm(window fyne.Window, formItems []*widget.FormItem) *widget.Form {
 }
 
 // handleFormSubmit handles the form submission in the preferences view.
-// It shows a waiting dialog while processing the form items, then updates the preferences accordingly.
-// If the test mode is enabled, it toggles it off temporarily and restores it afterwards.
-// It fetches members from local storage and shows a success dialog if successful.
-// If there is an error fetching the members, it shows an error dialog.
+// It updates the preferences from the form items and fetches the patreons in the background,
+// showing a waiting dialog that allows cancelling a pending authorization.
+// If the test mode is enabled, it toggles it off temporarily and restores it once the fetch finishes.
+// It shows a success dialog if the members were fetched, otherwise an error dialog with the reason.
 func handleFormSubmit(window fyne.Window, formItems []*widget.FormItem) {
-	waitingDialog := dialog.NewCustomWithoutButtons(commons.GetTranslation(commons.I18n.FetchingPatreons),
-		widget.NewLabel(fmt.Sprintf("%s...", commons.GetTranslation(commons.I18n.FetchingPatreons))), window)
-	waitingDialog.Show()
 	preferencesProcessing(formItems, "onSave")
 	testMode := commons.GetPreferences().BoolWithFallback(commons.TestMode, false)
 	testModeToogled := false
@@ -77,25 +339,29 @@ func handleFormSubmit(window fyne.Window, formItems []*widget.FormItem) {
 		commons.GetPreferences().SetBool(commons.TestMode, false)
 		testModeToogled = true
 	}
-	if members, _ := data.FetchMembersToLocalStorage(); members == nil {
-		waitingDialog.Hide()
-		dialog.NewError(fmt.Errorf(commons.GetTranslation(commons.I18n.ErrorFetchingPatreons)), window).Show()
-	} else {
-		waitingDialog.Hide()
-		showSuccessDialog(window)
-	}
 
-	if testModeToogled {
-		commons.GetPreferences().SetBool(commons.TestMode, true)
-	}
+	patreon, _ := data.GetSource(data.PatreonSourceID)
+	fetchMembers(window, []data.ParticipantSource{patreon}, func(result *data.FetchResult, err error) {
+		if testModeToogled {
+			commons.GetPreferences().SetBool(commons.TestMode, true)
+		}
+
+		if err != nil {
+			showFetchError(window, err)
+			return
+		}
+		showSuccessDialog(window, result)
+	})
 }
 
-// showSuccessDialog displays a success dialog with a custom message.
+// showSuccessDialog displays a success dialog with a custom message and the summary of the fetch.
 // It takes a fyne.Window as a parameter and shows the dialog on that window.
 // After the dialog is closed, it calls the MainMenu function to return to the main menu.
-func showSuccessDialog(window fyne.Window) {
+func showSuccessDialog(window fyne.Window, result *data.FetchResult) {
+	content := container.NewVBox(widget.NewLabel(commons.GetTranslation(commons.I18n.SuccessfulReceive)),
+		createFetchSummary(fmt.Sprintf(commons.GetTranslation(commons.I18n.FetchSummary), len(result.Members)), result))
 	dialogCustom := dialog.NewCustom(commons.GetTranslation(commons.I18n.Success),
-		commons.GetTranslation(commons.I18n.Close), widget.NewLabel(commons.GetTranslation(commons.I18n.SuccessfulReceive)), window)
+		commons.GetTranslation(commons.I18n.Close), content, window)
 	dialogCustom.SetOnClosed(func() {
 		MainMenu(window)
 	})
@@ -144,7 +410,7 @@ func createBackground() *canvas.Rectangle {
 
 // preferencesProcessing processes the form items based on the specified action.
 // It iterates over the form items and performs different actions on the widget.Entry items
-// based on the given action.
+// based on the given action. The settings of the campaign profiles are stored under the keys of the edited profile.
 //
 // Parameters:
 // - formItems: A slice of form items.
@@ -157,12 +423,30 @@ func preferencesProcessing(formItems []*widget.FormItem, action string) {
 	for _, item := range formItems {
 		entry, ok := item.Widget.(*widget.Entry)
 		if ok {
+			key := item.Text
+			if isProfileSetting(key) {
+				key = editedProfile.Key(key)
+			}
 			switch action {
 			case "onLoad":
-				entry.SetText(commons.GetPreferences().String(item.Text))
+				entry.SetText(commons.GetPreferences().String(key))
 			case "onSave":
-				commons.GetPreferences().SetString(item.Text, entry.Text)
+				// A newly pasted creator token has an unknown expiry
+				if item.Text == commons.CreatorAccessToken && entry.Text != commons.GetPreferences().String(key) {
+					commons.GetPreferences().RemoveValue(editedProfile.Key(commons.CreatorTokenExpiry))
+				}
+				commons.GetPreferences().SetString(key, entry.Text)
 			}
 		}
 	}
 }
+
+// isProfileSetting reports whether the setting is kept separately for every campaign profile.
+func isProfileSetting(key string) bool {
+	for _, setting := range commons.ProfileSettings {
+		if setting == key {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,11 @@
 package views
 
 import (
+	"fmt"
 	"image/color"
 	"pick-a-bro/internal/commons"
 	"pick-a-bro/internal/data"
+	"pick-a-bro/internal/lottery"
 	"strconv"
 	"strings"
 	"unicode"
@@ -11,6 +13,7 @@ import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
@@ -31,35 +34,46 @@ func SetRules(window fyne.Window) {
 // Returns: None
 func rules(window fyne.Window) {
 	headerContainer := createHeaderContainer()
+	snapshotRow := createSnapshotSelect(window)
 
 	chancesRule := createSelect()
 	chancesLabel := widget.NewLabel(commons.GetTranslation(commons.I18n.ChancesPerPatreon))
-	chancesPerUser := createEntry(commons.GetPreferences().IntWithFallback(commons.ChancesPerUser, 1), commons.ChancesPerUser)
+	chancesPerUser := createChancesEntry(commons.GetPreferences().FloatWithFallback(commons.ChancesPerUser, 1), func(chances float64) {
+		commons.GetPreferences().SetFloat(commons.ChancesPerUser, chances)
+	})
 	chancesContainer := container.NewHBox(chancesLabel, chancesPerUser)
 
 	membersList := data.GetMembersAndTiers()
-	tierEntries := container.NewHBox()
-	tierEntries.Add(chancesLabel)
-	for _, tier := range membersList.Tiers {
-		label := widget.NewLabel(tier.(string))
-		entry := createEntry(commons.GetPreferences().IntWithFallback("chances"+tier.(string), 1), "chances"+tier.(string))
-		tierEntries.Add(container.NewHBox(label, entry))
-	}
+	tierEntries := createTierEntries(chancesLabel, membersList.Tiers, membersList.Campaigns)
+	campaignEntries := createCampaignEntries(membersList.Campaigns)
 
-	if chancesRule.Selected == commons.GetTranslation(commons.ChancesRules[1]) {
-		chancesContainer.Hide()
-		tierEntries.Show()
-	} else {
-		tierEntries.Hide()
+	// The chances settings of every rule, only the ones of the selected rule are shown
+	ruleContainers := map[string]fyne.CanvasObject{
+		commons.ChancesRuleIDs.Equal:      chancesContainer,
+		commons.ChancesRuleIDs.ByTier:     tierEntries,
+		commons.ChancesRuleIDs.ByCampaign: campaignEntries,
+	}
+	showRuleContainer := func(rule string) {
+		for id, ruleContainer := range ruleContainers {
+			if id == rule {
+				ruleContainer.Show()
+				ruleContainer.Refresh()
+			} else {
+				ruleContainer.Hide()
+			}
+		}
 	}
+	showRuleContainer(lottery.GetChancesRule())
 
-	headerGrid, membersGrid := createGrid(membersList)
+	headerGrid, membersGrid := createGrid(window, membersList)
 
 	rulesViewContainer := container.NewVBox(
 		headerContainer,
+		snapshotRow,
 		chancesRule,
 		chancesContainer,
 		tierEntries,
+		campaignEntries,
 		headerGrid,
 		membersGrid,
 	)
@@ -71,72 +85,150 @@ func rules(window fyne.Window) {
 	confirmButtons := createConfirmButtons(window)
 
 	membersGrid.SetMinSize(fyne.NewSize(window.Canvas().Size().Width,
-		window.Canvas().Size().Height-(headerContainer.MinSize().Height+
+		window.Canvas().Size().Height-(headerContainer.MinSize().Height+snapshotRow.MinSize().Height+
 			chancesRule.MinSize().Height+
 			headerGrid.MinSize().Height+
 			confirmButtons.MinSize().Height)))
 	rulesView.Refresh()
 
-	chancesRule.OnChanged = func(value string) {
-		commons.GetPreferences().SetString(commons.ChancesRule, value)
-		if value == commons.GetTranslation(commons.ChancesRules[0]) {
-			tierEntries.Hide()
-			chancesContainer.Show()
-			membersGrid.SetMinSize(fyne.NewSize(commons.WindowWidth,
-				window.Canvas().Size().Height-(headerContainer.MinSize().Height+
-					chancesRule.MinSize().Height+
-					chancesContainer.MinSize().Height+
-					headerGrid.MinSize().Height+
-					confirmButtons.MinSize().Height)))
-		} else {
-			chancesContainer.Hide()
-			tierEntries.Show()
-			tierEntries.Refresh()
-			membersGrid.SetMinSize(fyne.NewSize(commons.WindowWidth,
-				window.Canvas().Size().Height-(headerContainer.MinSize().Height+
-					chancesRule.MinSize().Height+
-					tierEntries.MinSize().Height+
-					headerGrid.MinSize().Height+
-					confirmButtons.MinSize().Height)))
-		}
+	chancesRule.OnChanged = func(string) {
+		rule := commons.ChancesRules[chancesRule.SelectedIndex()]
+		commons.GetPreferences().SetString(commons.ChancesRule, rule)
+		showRuleContainer(rule)
+		membersGrid.SetMinSize(fyne.NewSize(commons.WindowWidth,
+			window.Canvas().Size().Height-(headerContainer.MinSize().Height+snapshotRow.MinSize().Height+
+				chancesRule.MinSize().Height+
+				ruleContainers[rule].MinSize().Height+
+				headerGrid.MinSize().Height+
+				confirmButtons.MinSize().Height)))
 	}
 	content := container.New(layout.NewStackLayout(), commons.GetBackgroundImage(), rulesView, confirmButtons)
 
 	window.SetContent(content)
 }
 
-// createHeaderContainer creates and returns a widget.Check that represents the header container.
-// The widget.Check allows the user to exclude winners based on the provided translation.
-// The value of the widget.Check is stored in the preferences using the commons.ExcludeWinners key.
-func createHeaderContainer() *widget.Check {
-	excludeWinners := widget.NewCheck(commons.GetTranslation(commons.I18n.ExcludeWinners), func(value bool) {
+// createHeaderContainer creates and returns the header container: a widget.Check that allows the user to exclude previous winners,
+// and an entry for the number of winners of the draw.
+// The widget.Check starts from and stores its value in the preferences using the commons.ExcludeWinners key,
+// and the number of winners using the commons.NumberOfWinners key.
+func createHeaderContainer() *fyne.Container {
+	excludeWinners := widget.NewCheck(commons.GetTranslation(commons.I18n.ExcludeWinners), nil)
+	excludeWinners.SetChecked(commons.GetPreferences().BoolWithFallback(commons.ExcludeWinners, false))
+	excludeWinners.OnChanged = func(value bool) {
 		commons.GetPreferences().SetBool(commons.ExcludeWinners, value)
+	}
+	numberOfWinners := createEntry(lottery.GetNumberOfWinners(), func(winners int) {
+		commons.GetPreferences().SetInt(commons.NumberOfWinners, winners)
 	})
-	return excludeWinners
+	return container.NewHBox(excludeWinners, layout.NewSpacer(), widget.NewLabel(commons.GetTranslation(commons.I18n.NumberOfWinners)), numberOfWinners)
+}
+
+// createSnapshotSelect creates a row to pick the patreons list the draw uses: the latest fetch or one of the stored snapshots,
+// labelled with their date and the start of their hash. Picking a list loads it and redraws the rules view.
+// The row is empty when none of the sources of the draw keeps snapshots.
+func createSnapshotSelect(window fyne.Window) *fyne.Container {
+	if !data.DrawUsesSnapshots() {
+		return container.NewHBox()
+	}
+	snapshots := data.ListSnapshots()
+	ids := []string{""}
+	options := []string{commons.GetTranslation(commons.I18n.LatestSnapshot)}
+	selected := 0
+	for _, snapshot := range snapshots {
+		if snapshot.ID == data.GetSelectedSnapshot() {
+			selected = len(ids)
+		}
+		ids = append(ids, snapshot.ID)
+		options = append(options, fmt.Sprintf("%s · %s", snapshot.CreatedAt.Format("2006-01-02 15:04:05"), snapshot.Hash))
+	}
+
+	selectWidget := widget.NewSelect(options, nil)
+	selectWidget.SetSelectedIndex(selected)
+	selectWidget.OnChanged = func(string) {
+		if !data.SelectSnapshot(ids[selectWidget.SelectedIndex()]) {
+			data.SelectSnapshot("")
+			rules(window)
+			dialog.NewInformation(commons.GetTranslation(commons.I18n.MissingData), commons.GetTranslation(commons.I18n.SnapshotLoadFailed), window).Show()
+			return
+		}
+		rules(window)
+	}
+	return container.NewHBox(widget.NewLabel(commons.GetTranslation(commons.I18n.Snapshot)), selectWidget)
 }
 
-// createSelect creates and returns a new widget.Select with options populated from commons.ChancesRules.
-// It sets the selected option based on the user's preferences.
+// createSelect creates and returns a new widget.Select with the translated labels of commons.ChancesRules.
+// It selects the rule stored in the user's preferences; the options follow the order of commons.ChancesRules,
+// so the selected index maps back to the rule ID.
 func createSelect() *widget.Select {
 	options := make([]string, len(commons.ChancesRules))
-
-	copy(options, commons.ChancesRules)
-	for i, option := range options {
-		options[i] = commons.GetTranslation(option)
+	selected := 0
+	storedRule := lottery.GetChancesRule()
+	for i, rule := range commons.ChancesRules {
+		options[i] = commons.GetTranslation(commons.ChancesRuleLabels[rule])
+		if rule == storedRule {
+			selected = i
+		}
 	}
 	selectWidget := widget.NewSelect(options, nil)
-	selectWidget.SetSelected(commons.GetPreferences().StringWithFallback(commons.ChancesRule, commons.GetTranslation(commons.ChancesRules[0])))
+	selectWidget.SetSelectedIndex(selected)
 	return selectWidget
 }
 
-// createEntry creates a new widget.Entry with the specified default value and preference key.
+// createTierEntries creates a chances entry for each tier, storing the chances under the tier ID.
+// When the tiers come from several campaigns, each tier is labelled with its campaign too.
+// If chances are stored for tiers that are no longer in the list, a warning naming them is shown below the entries.
+func createTierEntries(chancesLabel *widget.Label, tiers []data.Tier, campaigns []data.Campaign) *fyne.Container {
+	campaignNames := map[string]string{}
+	for _, campaign := range campaigns {
+		campaignNames[campaign.ID] = campaign.Name
+	}
+
+	entries := container.NewHBox()
+	entries.Add(chancesLabel)
+	for _, tier := range tiers {
+		tier := tier
+		label := widget.NewLabel(tier.Title)
+		if name, found := campaignNames[tier.CampaignID]; found && len(campaigns) > 1 {
+			label.SetText(fmt.Sprintf("%s (%s)", tier.Title, name))
+		}
+		entry := createChancesEntry(data.GetTierChances(tier.ID), func(chances float64) {
+			data.SetTierChances(tier, chances)
+		})
+		entries.Add(container.NewHBox(label, entry))
+	}
+
+	tierEntries := container.NewVBox(entries)
+	if stale := data.StaleTierChances(tiers); len(stale) > 0 {
+		warning := widget.NewLabelWithStyle(fmt.Sprintf(commons.GetTranslation(commons.I18n.StaleTierChances), strings.Join(stale, ", ")),
+			fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+		warning.Wrapping = fyne.TextWrapWord
+		tierEntries.Add(warning)
+	}
+	return tierEntries
+}
+
+// createCampaignEntries creates a chances entry for each campaign, storing the chances under the campaign ID.
+// A patreon supporting several campaigns gets the chances of each of them.
+func createCampaignEntries(campaigns []data.Campaign) *fyne.Container {
+	entries := container.NewHBox(widget.NewLabel(commons.GetTranslation(commons.I18n.ChancesPerPatreon)))
+	for _, campaign := range campaigns {
+		campaign := campaign
+		entry := createChancesEntry(data.GetCampaignChances(campaign.ID), func(chances float64) {
+			data.SetCampaignChances(campaign.ID, chances)
+		})
+		entries.Add(container.NewHBox(widget.NewLabel(campaign.Name), entry))
+	}
+	return entries
+}
+
+// createEntry creates a new widget.Entry with the specified default value and change handler.
 // The default value is converted to a string and set as the initial text of the entry.
 // The entry's OnChanged event is set to a function that updates the entry's text based on user input,
-// filters out non-digit characters, and updates the corresponding preference value.
+// filters out non-digit characters, and passes the new value to the change handler.
 // If the entry's text is empty, it is set to "1" as the default value.
-// The filtered text is then converted to an integer and stored in the preference with the specified key.
+// The filtered text is then converted to an integer and passed to onChanged, which stores it.
 // The created entry is returned.
-func createEntry(defaultValue int, preferenceKey string) *widget.Entry {
+func createEntry(defaultValue int, onChanged func(chances int)) *widget.Entry {
 	entry := widget.NewEntry()
 	entry.Text = strconv.Itoa(defaultValue)
 	entry.OnChanged = func(value string) {
@@ -157,23 +249,101 @@ func createEntry(defaultValue int, preferenceKey string) *widget.Entry {
 			entry.SetText(filtered)
 		}
 		chances, _ := strconv.Atoi(filtered)
-		commons.GetPreferences().SetInt(preferenceKey, chances)
+		onChanged(chances)
 	}
 	return entry
 }
 
-// createGridCells creates grid cells for each member in the given membersList.
-// It takes a pointer to a MembersList struct and returns a slice of fyne.CanvasObject.
-func createGridCells(membersList *data.MembersList) []fyne.CanvasObject {
+// createChancesEntry creates a new widget.Entry for chances, which may be fractions, e.g. 0.5 or 2.5.
+// Like createEntry, it discards any other character than digits and a single decimal point, a comma being read as the point,
+// and sets the text to "1" if it is emptied. Chances of 0 leave the concerned members out of the draw.
+// The parsed value is passed to onChanged, which stores it.
+func createChancesEntry(defaultValue float64, onChanged func(chances float64)) *widget.Entry {
+	entry := widget.NewEntry()
+	entry.Text = strconv.FormatFloat(defaultValue, 'f', -1, 64)
+	entry.OnChanged = func(value string) {
+		if value == "" {
+			entry.Text = "1"
+			return
+		}
+
+		point := false
+		filtered := strings.Map(func(r rune) rune {
+			if r == ',' {
+				r = '.'
+			}
+			if unicode.IsDigit(r) || (r == '.' && !point) {
+				point = point || r == '.'
+				return r
+			}
+			return -1 // Discard this rune
+		}, value)
+
+		// Prevent infinite loop by checking if filtering is necessary
+		if filtered != value {
+			entry.SetText(filtered)
+		}
+		if chances, err := strconv.ParseFloat(filtered, 64); err == nil {
+			onChanged(chances)
+		}
+	}
+	return entry
+}
+
+// createGridCells creates grid cells for each member in the given membersList: the name, the tier with the entries
+// of a member with several entries and, when the draw uses several sources, the source of the member, and the buttons to edit the member and to remove it from the draw or restore it.
+// The members added, edited or removed by the operator are marked next to their name; removed members are greyed out.
+func createGridCells(window fyne.Window, membersList *data.MembersList) []fyne.CanvasObject {
 	members := []fyne.CanvasObject{}
+	severalSources := len(data.DrawSources()) > 1
 	for _, d := range membersList.PatreonMembers {
-		color := membersList.ColorCode[d.Tier]
-		members = append(members, makeCellWithBackground(widget.NewLabel(d.FullName).Text, color),
-			makeCellWithBackground(widget.NewLabel(d.Tier).Text, color))
+		d := d
+		color := membersList.ColorCode[d.TierID]
+		name := d.FullName
+		if d.Override != "" {
+			name = fmt.Sprintf("%s (%s)", d.FullName, commons.GetTranslation(overrideLabels[d.Override]))
+		}
+		tier := d.Tier
+		if d.EntryCount() > 1 {
+			tier = fmt.Sprintf("%s ×%d", d.Tier, d.EntryCount())
+		}
+		if severalSources && d.Source != "" {
+			tier = fmt.Sprintf("%s · %s", tier, data.SourceName(d.Source))
+		}
+
+		editButton := widget.NewButton(commons.GetTranslation(commons.I18n.Edit), func() {
+			showParticipantDialog(window, &d)
+		})
+		removeButton := widget.NewButton(commons.GetTranslation(commons.I18n.Remove), func() {
+			data.RemoveParticipant(d)
+			rules(window)
+		})
+		if d.Override == data.OverrideRemoved {
+			color = removedColor
+			editButton.Disable()
+			removeButton.SetText(commons.GetTranslation(commons.I18n.Restore))
+			removeButton.OnTapped = func() {
+				data.RestoreParticipant(d)
+				rules(window)
+			}
+		}
+
+		members = append(members, makeCellWithBackground(name, color), makeCellWithBackground(tier, color),
+			container.NewHBox(editButton, removeButton))
 	}
 	return members
 }
 
+// overrideLabels maps every kind of change of the operator to the translation key of its marker.
+var overrideLabels = map[string]string{
+	data.OverrideAdded:   commons.I18n.OverrideAdded,
+	data.OverrideEdited:  commons.I18n.OverrideEdited,
+	data.OverrideRemoved: commons.I18n.OverrideRemoved,
+}
+
+// removedColor is the background of the members removed from the draw.
+var removedColor = color.Gray{Y: 60}
+
 // Function to create a widget with a background color
 // makeCellWithBackground creates a fyne.CanvasObject that consists of a label with the specified text and a background color.
 // The label displays the given text, and the background is a rectangle filled with the specified color.
@@ -185,17 +355,90 @@ func makeCellWithBackground(text string, color color.Color) fyne.CanvasObject {
 }
 
 // createGrid creates a grid layout containing the header and members grid.
+// The header holds the buttons to add a participant by hand and to reset the changes to the list.
 // It takes a pointer to a MembersList and returns a Container and Scroll widget.
-func createGrid(membersList *data.MembersList) (*fyne.Container, *container.Scroll) {
-	cells := createGridCells(membersList)
+func createGrid(window fyne.Window, membersList *data.MembersList) (*fyne.Container, *container.Scroll) {
+	cells := createGridCells(window, membersList)
+
+	addButton := widget.NewButton(commons.GetTranslation(commons.I18n.AddParticipant), func() {
+		showParticipantDialog(window, nil)
+	})
+	resetButton := widget.NewButton(commons.GetTranslation(commons.I18n.ResetChanges), func() {
+		data.ResetOverrides()
+		rules(window)
+	})
+	if !data.HasOverrides() {
+		resetButton.Disable()
+	}
 
 	headerGrid := container.NewHBox(
 		widget.NewLabel(commons.Fellowship),
+		layout.NewSpacer(),
+		addButton,
+		resetButton,
 	)
-	membersGrid := container.NewVScroll(container.NewGridWithColumns(2, cells...))
+	membersGrid := container.NewVScroll(container.NewGridWithColumns(3, cells...))
 	return headerGrid, membersGrid
 }
 
+// showParticipantDialog shows a dialog to set the tier and the entries of the member, or of a new participant added by hand if member is nil.
+// A new participant also needs a name. Confirming the dialog stores the change, kept apart from the fetched list, and redraws the rules view.
+func showParticipantDialog(window fyne.Window, member *data.PatreonMember) {
+	tiers := data.GetMembersAndTiers().Tiers
+	tierIDs := []string{}
+	tierTitles := []string{}
+	hasNoTier := false
+	for _, tier := range tiers {
+		tierIDs = append(tierIDs, tier.ID)
+		tierTitles = append(tierTitles, tier.Title)
+		hasNoTier = hasNoTier || tier.ID == commons.NoTierID
+	}
+	if !hasNoTier {
+		tierIDs = append(tierIDs, commons.NoTierID)
+		tierTitles = append(tierTitles, data.GetNoTierName())
+	}
+
+	tierSelect := widget.NewSelect(tierTitles, nil)
+	tierSelect.SetSelectedIndex(0)
+	entries := 1
+	if member != nil {
+		entries = member.EntryCount()
+		for i, id := range tierIDs {
+			if id == member.TierID {
+				tierSelect.SetSelectedIndex(i)
+			}
+		}
+	}
+	entriesEntry := createEntry(entries, func(value int) {
+		entries = value
+	})
+
+	nameEntry := widget.NewEntry()
+	formItems := []*widget.FormItem{
+		widget.NewFormItem(commons.GetTranslation(commons.I18n.ParticipantTier), tierSelect),
+		widget.NewFormItem(commons.GetTranslation(commons.I18n.ParticipantEntries), entriesEntry),
+	}
+	title := commons.GetTranslation(commons.I18n.AddParticipant)
+	if member == nil {
+		formItems = append([]*widget.FormItem{widget.NewFormItem(commons.GetTranslation(commons.I18n.ParticipantName), nameEntry)}, formItems...)
+	} else {
+		title = member.FullName
+	}
+
+	dialog.ShowForm(title, commons.GetTranslation(commons.I18n.Save), commons.GetTranslation(commons.I18n.Cancel), formItems, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		tierID := tierIDs[tierSelect.SelectedIndex()]
+		if member != nil {
+			data.EditParticipant(*member, tierID, entries)
+		} else if name := strings.TrimSpace(nameEntry.Text); name != "" {
+			data.AddParticipant(name, tierID, entries)
+		}
+		rules(window)
+	}, window)
+}
+
 // createConfirmButtons creates and returns a container with confirm buttons for the window.
 // It takes a fyne.Window as input and returns a *fyne.Container.
 func createConfirmButtons(window fyne.Window) *fyne.Container {
@@ -203,7 +446,9 @@ func createConfirmButtons(window fyne.Window) *fyne.Container {
 		SetLottery(window)
 	})
 
+	// Cancelling aborts the draw, so the members removed for it are brought back
 	cancelDraw := widget.NewButton(commons.GetTranslation(commons.I18n.Cancel), func() {
+		data.ClearDrawRemovals()
 		MainMenu(window)
 	})
 
@@ -0,0 +1,87 @@
+package views
+
+import (
+	"fmt"
+	"pick-a-bro/internal/commons"
+	"pick-a-bro/internal/data"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// createSourcesSelector creates a checkbox for every registered participant source, except the sample source of the test mode,
+// checked for the sources the draw uses. The participants of every checked source are put together in the draw.
+// A source with settings gets a button that opens its settings dialog, and a source the participants enter themselves
+// gets a button that opens its entry window, see openEntries.
+func createSourcesSelector(window fyne.Window) fyne.CanvasObject {
+	names := []string{}
+	ids := map[string]string{}
+	sourceButtons := container.NewHBox()
+	for _, source := range data.GetSources() {
+		if source.Capabilities().Sample {
+			continue
+		}
+		names = append(names, source.Name())
+		ids[source.Name()] = source.ID()
+		if len(source.ConfigFields()) > 0 {
+			source := source
+			sourceButtons.Add(widget.NewButton(fmt.Sprintf(commons.GetTranslation(commons.I18n.SourceSettings), source.Name()), func() {
+				showSourceSettingsDialog(window, source)
+			}))
+		}
+		if entrySource, ok := source.(data.EntrySource); ok {
+			sourceButtons.Add(widget.NewButton(fmt.Sprintf(commons.GetTranslation(commons.I18n.OpenEntries), source.Name()), func() {
+				openEntries(window, entrySource)
+			}))
+		}
+	}
+
+	selected := []string{}
+	for _, id := range data.GetSelectedSourceIDs() {
+		selected = append(selected, data.SourceName(id))
+	}
+
+	checkGroup := widget.NewCheckGroup(names, func(checked []string) {
+		selectedIDs := make([]string, 0, len(checked))
+		for _, name := range checked {
+			selectedIDs = append(selectedIDs, ids[name])
+		}
+		data.SetSelectedSourceIDs(selectedIDs)
+	})
+	checkGroup.Horizontal = true
+	checkGroup.SetSelected(selected)
+
+	return container.NewVBox(widget.NewLabel(commons.GetTranslation(commons.I18n.Sources)), checkGroup, sourceButtons)
+}
+
+// showSourceSettingsDialog shows an entry for every setting of the source, filled with its stored value.
+// Secret settings are shown in a password entry. Saving the dialog stores every value in the preferences.
+func showSourceSettingsDialog(window fyne.Window, source data.ParticipantSource) {
+	fields := source.ConfigFields()
+	entries := make([]*widget.Entry, len(fields))
+	formItems := make([]*widget.FormItem, len(fields))
+	for i, field := range fields {
+		if field.Secret {
+			entries[i] = widget.NewPasswordEntry()
+		} else {
+			entries[i] = widget.NewEntry()
+		}
+		entries[i].SetPlaceHolder(field.Placeholder)
+		entries[i].SetText(commons.GetPreferences().String(data.SourceSettingKey(source.ID(), field.Key)))
+		formItems[i] = widget.NewFormItem(field.Label, entries[i])
+	}
+
+	settingsDialog := dialog.NewForm(fmt.Sprintf(commons.GetTranslation(commons.I18n.SourceSettings), source.Name()), commons.GetTranslation(commons.I18n.Save),
+		commons.GetTranslation(commons.I18n.Cancel), formItems, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			for i, field := range fields {
+				commons.GetPreferences().SetString(data.SourceSettingKey(source.ID(), field.Key), entries[i].Text)
+			}
+		}, window)
+	settingsDialog.Resize(fyne.NewSize(500, 300))
+	settingsDialog.Show()
+}